@@ -0,0 +1,251 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/tonya11en/mysql-operator/pkg/apis/myproject"
+	"go.uber.org/zap"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Key of the CA's private key in the certificate Secrets written by the
+// operator. The CA certificate is under myproject.CACertKey.
+const caKeyKey = "ca.key"
+
+const (
+	caValidity   = 10 * 365 * 24 * time.Hour
+	certValidity = 365 * 24 * time.Hour
+	// Certificates closer than this to expiry are replaced.
+	certRenewBefore = 30 * 24 * time.Hour
+)
+
+// certBundle is a CA together with a certificate it signed, all PEM encoded.
+type certBundle struct {
+	caCert []byte
+	caKey  []byte
+	cert   []byte
+	key    []byte
+}
+
+func (b *certBundle) secretData() map[string][]byte {
+	return map[string][]byte{
+		myproject.CACertKey: b.caCert,
+		caKeyKey:            b.caKey,
+		v1.TLSCertKey:       b.cert,
+		v1.TLSPrivateKeyKey: b.key,
+	}
+}
+
+func bundleFromSecret(secret *v1.Secret) *certBundle {
+	return &certBundle{
+		caCert: secret.Data[myproject.CACertKey],
+		caKey:  secret.Data[caKeyKey],
+		cert:   secret.Data[v1.TLSCertKey],
+		key:    secret.Data[v1.TLSPrivateKeyKey],
+	}
+}
+
+// valid reports whether the bundle holds a certificate for every one of
+// dnsNames, signed by its CA, that is not about to expire.
+func (b *certBundle) valid(dnsNames []string) bool {
+	caCert, err := parseCertPEM(b.caCert)
+	if err != nil || time.Until(caCert.NotAfter) < certRenewBefore {
+		return false
+	}
+	cert, err := parseCertPEM(b.cert)
+	if err != nil || time.Until(cert.NotAfter) < certRenewBefore {
+		return false
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: certPool(caCert), KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+		return false
+	}
+	for _, name := range dnsNames {
+		if cert.VerifyHostname(name) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func certPool(certs ...*x509.Certificate) *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, c := range certs {
+		pool.AddCert(c)
+	}
+	return pool
+}
+
+func parseCertPEM(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func parseKeyPEM(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "RSA PRIVATE KEY" {
+		return nil, fmt.Errorf("no private key found")
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+func encodeCert(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func encodeKey(key *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// newCA creates a self-signed certificate authority.
+func newCA(commonName string) (certPEM []byte, keyPEM []byte, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encodeCert(der), encodeKey(key), nil
+}
+
+// newServingCert creates a server certificate for dnsNames signed by the CA.
+func newServingCert(caCertPEM []byte, caKeyPEM []byte, dnsNames []string) (certPEM []byte, keyPEM []byte, err error) {
+	caCert, err := parseCertPEM(caCertPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	caKey, err := parseKeyPEM(caKeyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(certValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encodeCert(der), encodeKey(key), nil
+}
+
+// ensureCertSecret returns the certificates stored in the named Secret,
+// creating or replacing them when they are missing, invalid or close to
+// expiry. The CA is kept when it is still good so clients trusting it keep
+// working. Several operator replicas may race here; the loser of a create or
+// update re-reads the winner's certificates. A Secret that is created gets
+// labels.
+func ensureCertSecret(clientset kubernetes.Interface, namespace string, name string, dnsNames []string, labels map[string]string) (*certBundle, error) {
+	secrets := clientset.CoreV1().Secrets(namespace)
+	for attempt := 0; attempt < 3; attempt++ {
+		secret, err := secrets.Get(context.TODO(), name, meta_v1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		exists := err == nil
+
+		var bundle *certBundle
+		if exists {
+			bundle = bundleFromSecret(secret)
+			if bundle.valid(dnsNames) {
+				return bundle, nil
+			}
+		} else {
+			bundle = &certBundle{}
+		}
+
+		if caCert, err := parseCertPEM(bundle.caCert); err != nil || time.Until(caCert.NotAfter) < certRenewBefore {
+			log.Info("generating certificate authority", zap.String("secret", name))
+			if bundle.caCert, bundle.caKey, err = newCA(name + "-ca"); err != nil {
+				return nil, fmt.Errorf("failed to generate CA. %+v", err)
+			}
+		}
+		log.Info("generating serving certificate", zap.String("secret", name), zap.Strings("dnsNames", dnsNames))
+		if bundle.cert, bundle.key, err = newServingCert(bundle.caCert, bundle.caKey, dnsNames); err != nil {
+			return nil, fmt.Errorf("failed to generate serving certificate. %+v", err)
+		}
+
+		if exists {
+			secret = secret.DeepCopy()
+			secret.Data = bundle.secretData()
+			_, err = secrets.Update(context.TODO(), secret, meta_v1.UpdateOptions{})
+		} else {
+			_, err = secrets.Create(context.TODO(), &v1.Secret{
+				ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+				Type:       v1.SecretTypeOpaque,
+				Data:       bundle.secretData(),
+			}, meta_v1.CreateOptions{})
+		}
+		if errors.IsConflict(err) || errors.IsAlreadyExists(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to store certificates in secret %s. %+v", name, err)
+		}
+		return bundle, nil
+	}
+	return nil, fmt.Errorf("gave up storing certificates in secret %s after repeated conflicts", name)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/tonya11en/mysql-operator/pkg/apis/myproject"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// The VolumeSnapshot API the operator takes final snapshots with.
+var volumeSnapshotResource = schema.GroupVersionResource{
+	Group:    "snapshot.storage.k8s.io",
+	Version:  "v1",
+	Resource: "volumesnapshots",
+}
+
+func backupCommand() *command {
+	var snapshotClass string
+	var waitReady bool
+	timeout := 10 * time.Minute
+	return &command{
+		name:    "backup",
+		args:    "<name>",
+		summary: "Take a VolumeSnapshot of a MySql instance's volume",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&snapshotClass, "snapshot-class", "", "VolumeSnapshotClass to use instead of the default one")
+			fs.BoolVar(&waitReady, "wait", false, "wait for the snapshot to become ready to use")
+			fs.DurationVar(&timeout, "timeout", timeout, "how long --wait waits")
+		},
+		run: func(p *plugin, args []string) error {
+			name, err := nameArg(args)
+			if err != nil {
+				return err
+			}
+			s, err := p.getMySql(name)
+			if err != nil {
+				return err
+			}
+			pvcName := s.Name + myproject.PVCNameSuffix
+			if _, err := p.clientset.CoreV1().PersistentVolumeClaims(s.Namespace).Get(context.TODO(), pvcName, meta_v1.GetOptions{}); err != nil {
+				return fmt.Errorf("failed to get the volume of mysql %s. %+v", s.Name, err)
+			}
+
+			spec := map[string]interface{}{
+				"source": map[string]interface{}{
+					"persistentVolumeClaimName": pvcName,
+				},
+			}
+			if snapshotClass != "" {
+				spec["volumeSnapshotClassName"] = snapshotClass
+			}
+			snapshotName := fmt.Sprintf("%s-backup-%s", pvcName, time.Now().UTC().Format("20060102150405"))
+			snapshot := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": volumeSnapshotResource.GroupVersion().String(),
+				"kind":       "VolumeSnapshot",
+				"metadata": map[string]interface{}{
+					"name":      snapshotName,
+					"namespace": s.Namespace,
+					"labels": map[string]interface{}{
+						myproject.InstanceLabel: s.Name,
+					},
+				},
+				"spec": spec,
+			}}
+			snapshots := p.dynamic.Resource(volumeSnapshotResource).Namespace(s.Namespace)
+			if _, err := snapshots.Create(context.TODO(), snapshot, meta_v1.CreateOptions{}); err != nil {
+				return fmt.Errorf("failed to create volume snapshot. %+v", err)
+			}
+			fmt.Printf("volumesnapshot/%s created\n", snapshotName)
+			if !waitReady {
+				return nil
+			}
+
+			err = wait.PollImmediate(2*time.Second, timeout, func() (bool, error) {
+				snapshot, err := snapshots.Get(context.TODO(), snapshotName, meta_v1.GetOptions{})
+				if err != nil {
+					return false, err
+				}
+				if message, found, _ := unstructured.NestedString(snapshot.Object, "status", "error", "message"); found {
+					return false, fmt.Errorf("snapshot failed: %s", message)
+				}
+				ready, _, _ := unstructured.NestedBool(snapshot.Object, "status", "readyToUse")
+				return ready, nil
+			})
+			if err != nil {
+				return fmt.Errorf("volumesnapshot %s did not become ready. %+v", snapshotName, err)
+			}
+			fmt.Printf("volumesnapshot/%s ready\n", snapshotName)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,333 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/tonya11en/mysql-operator/pkg/apis/myproject"
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	"k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+func infoCommand() *command {
+	var showPassword bool
+	return &command{
+		name:    "info",
+		args:    "<name>",
+		summary: "Print how to connect to a MySql instance",
+		flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&showPassword, "show-password", false, "print the root password instead of where it is kept")
+		},
+		run: func(p *plugin, args []string) error {
+			name, err := nameArg(args)
+			if err != nil {
+				return err
+			}
+			s, err := p.getMySql(name)
+			if err != nil {
+				return err
+			}
+
+			port := servicePort(s)
+			w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+			fmt.Fprintf(w, "Name:\t%s\n", s.Name)
+			fmt.Fprintf(w, "Namespace:\t%s\n", s.Namespace)
+			fmt.Fprintf(w, "Phase:\t%s\n", orNone(string(s.Status.Phase)))
+			fmt.Fprintf(w, "Host:\t%s.%s.svc.cluster.local\n", s.Name, s.Namespace)
+			fmt.Fprintf(w, "Port:\t%d\n", port)
+			if s.Status.ExternalAddress != "" {
+				fmt.Fprintf(w, "External address:\t%s:%d\n", s.Status.ExternalAddress, port)
+			}
+			fmt.Fprintf(w, "User:\troot\n")
+			switch ref := s.Spec.Credentials.RootPasswordSecretRef; {
+			case showPassword:
+				password, err := p.rootPassword(s)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(w, "Password:\t%s\n", password)
+			case ref != nil:
+				fmt.Fprintf(w, "Password:\tkey %s of Secret %s\n", ref.Key, ref.Name)
+			default:
+				fmt.Fprintf(w, "Password:\tspec.credentials.rootPassword\n")
+			}
+			switch {
+			case !s.Spec.TLS.Enabled:
+				fmt.Fprintf(w, "TLS:\tdisabled\n")
+			case s.Spec.TLS.RequireSecureTransport:
+				fmt.Fprintf(w, "TLS:\trequired, CA certificate in key %s of Secret %s\n", myproject.CACertKey, s.Name+myproject.CASecretSuffix)
+			default:
+				fmt.Fprintf(w, "TLS:\toptional, CA certificate in key %s of Secret %s\n", myproject.CACertKey, s.Name+myproject.CASecretSuffix)
+			}
+			fmt.Fprintf(w, "Connect:\tkubectl mysql shell %s -n %s\n", s.Name, s.Namespace)
+			return w.Flush()
+		},
+	}
+}
+
+func shellCommand() *command {
+	client := "mysql"
+	return &command{
+		name:    "shell",
+		args:    "<name> [-- <mysql arguments>]",
+		summary: "Open a mysql client shell as root against a MySql instance",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&client, "client", client, "mysql client binary to run")
+		},
+		run: func(p *plugin, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("expected the name of a MySql")
+			}
+			s, err := p.getMySql(args[0])
+			if err != nil {
+				return err
+			}
+			password, err := p.rootPassword(s)
+			if err != nil {
+				return err
+			}
+			pod, err := p.runningPod(s)
+			if err != nil {
+				return err
+			}
+
+			clientArgs := []string{"--user=root", "--host=127.0.0.1"}
+			if s.Spec.TLS.Enabled {
+				caFile, err := p.writeCACert(s)
+				if err != nil {
+					return err
+				}
+				defer os.Remove(caFile)
+				clientArgs = append(clientArgs, "--ssl-ca="+caFile)
+			}
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			forwarder, errCh, err := p.forward(pod, []string{"127.0.0.1"}, fmt.Sprintf("0:%d", mysqlPort), stopCh, ioutil.Discard)
+			if err != nil {
+				return err
+			}
+			ports, err := forwarder.GetPorts()
+			if err != nil {
+				return err
+			}
+			clientArgs = append(clientArgs, "--port="+strconv.Itoa(int(ports[0].Local)))
+
+			cmd := exec.Command(client, append(clientArgs, args[1:]...)...)
+			// The password stays out of the process list.
+			cmd.Env = append(os.Environ(), "MYSQL_PWD="+password)
+			cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+			// Ctrl-C is meant for the client, not for the forward it runs over.
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt)
+			defer signal.Stop(signals)
+
+			if err := cmd.Start(); err != nil {
+				return fmt.Errorf("failed to run %s. %+v", client, err)
+			}
+			done := make(chan error, 1)
+			go func() { done <- cmd.Wait() }()
+			for {
+				select {
+				case err := <-done:
+					if exitErr, ok := err.(*exec.ExitError); ok {
+						return exitCode(exitErr.ExitCode())
+					}
+					return err
+				case err := <-errCh:
+					cmd.Process.Kill()
+					return fmt.Errorf("lost the port forward to pod %s. %+v", pod.Name, err)
+				case <-signals:
+				}
+			}
+		},
+	}
+}
+
+func portForwardCommand() *command {
+	address := "127.0.0.1"
+	port := mysqlPort
+	return &command{
+		name:    "port-forward",
+		args:    "<name>",
+		summary: "Forward a local port to a MySql instance until interrupted",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&address, "address", address, "local address to listen on")
+			fs.IntVar(&port, "port", port, "local port to listen on, 0 picks a free one")
+		},
+		run: func(p *plugin, args []string) error {
+			name, err := nameArg(args)
+			if err != nil {
+				return err
+			}
+			s, err := p.getMySql(name)
+			if err != nil {
+				return err
+			}
+			pod, err := p.runningPod(s)
+			if err != nil {
+				return err
+			}
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			forwarder, errCh, err := p.forward(pod, []string{address}, fmt.Sprintf("%d:%d", port, mysqlPort), stopCh, ioutil.Discard)
+			if err != nil {
+				return err
+			}
+			ports, err := forwarder.GetPorts()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Forwarding %s:%d to mysql/%s (pod %s), press Ctrl-C to stop\n", address, ports[0].Local, s.Name, pod.Name)
+
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+			defer signal.Stop(signals)
+			select {
+			case <-signals:
+				return nil
+			case err := <-errCh:
+				return fmt.Errorf("lost the port forward to pod %s. %+v", pod.Name, err)
+			}
+		},
+	}
+}
+
+func (p *plugin) getMySql(name string) (*mysql.MySql, error) {
+	s, err := p.mysqls.MyprojectV1beta1().MySqls(p.namespace).Get(context.TODO(), name, meta_v1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mysql %s. %+v", name, err)
+	}
+	return s, nil
+}
+
+// The port clients reach an instance's Service on.
+func servicePort(s *mysql.MySql) int32 {
+	if len(s.Spec.Service.Ports) > 0 {
+		return s.Spec.Service.Ports[0].Port
+	}
+	return mysqlPort
+}
+
+// The root password of an instance, read from its Secret when it references
+// one.
+func (p *plugin) rootPassword(s *mysql.MySql) (string, error) {
+	ref := s.Spec.Credentials.RootPasswordSecretRef
+	if ref == nil {
+		return s.Spec.Credentials.RootPassword, nil
+	}
+	secret, err := p.clientset.CoreV1().Secrets(s.Namespace).Get(context.TODO(), ref.Name, meta_v1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to read the root password from secret %s. %+v", ref.Name, err)
+	}
+	password, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %s", ref.Name, ref.Key)
+	}
+	return string(password), nil
+}
+
+// Write the CA certificate the operator publishes for an instance to a
+// temporary file, for the client to verify the server with.
+func (p *plugin) writeCACert(s *mysql.MySql) (string, error) {
+	name := s.Name + myproject.CASecretSuffix
+	secret, err := p.clientset.CoreV1().Secrets(s.Namespace).Get(context.TODO(), name, meta_v1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to read the CA certificate from secret %s. %+v", name, err)
+	}
+	f, err := ioutil.TempFile("", name+"-*.crt")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.Write(secret.Data[myproject.CACertKey]); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// A ready pod of an instance.
+func (p *plugin) runningPod(s *mysql.MySql) (*v1.Pod, error) {
+	selector := labels.SelectorFromSet(labels.Set{myproject.ManagedByLabel: myproject.ManagedByValue, myproject.InstanceLabel: s.Name})
+	pods, err := p.clientset.CoreV1().Pods(s.Namespace).List(context.TODO(), meta_v1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods. %+v", err)
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp != nil || pod.Status.Phase != v1.PodRunning {
+			continue
+		}
+		for _, c := range pod.Status.Conditions {
+			if c.Type == v1.PodReady && c.Status == v1.ConditionTrue {
+				return pod, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("mysql %s has no ready pod", s.Name)
+}
+
+// Forward port, in kubectl's local:remote form, to a pod. It returns once
+// the local listener is up; the channel reports a forward that fails later.
+func (p *plugin) forward(pod *v1.Pod, addresses []string, port string, stopCh chan struct{}, out io.Writer) (*portforward.PortForwarder, <-chan error, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(p.config)
+	if err != nil {
+		return nil, nil, err
+	}
+	url := p.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("portforward").
+		URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	readyCh := make(chan struct{})
+	forwarder, err := portforward.NewOnAddresses(dialer, addresses, []string{port}, stopCh, readyCh, out, os.Stderr)
+	if err != nil {
+		return nil, nil, err
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- forwarder.ForwardPorts()
+	}()
+	select {
+	case <-readyCh:
+		return forwarder, errCh, nil
+	case err := <-errCh:
+		return nil, nil, fmt.Errorf("failed to forward to pod %s. %+v", pod.Name, err)
+	}
+}
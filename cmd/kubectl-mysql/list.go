@@ -0,0 +1,99 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	"k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+func listCommand() *command {
+	var allNamespaces bool
+	return &command{
+		name:    "list",
+		summary: "List MySql instances with their status",
+		flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&allNamespaces, "all-namespaces", false, "list instances in every namespace")
+			fs.BoolVar(&allNamespaces, "A", false, "shorthand for --all-namespaces")
+		},
+		run: func(p *plugin, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("list takes no arguments")
+			}
+			namespace := p.namespace
+			if allNamespaces {
+				namespace = v1.NamespaceAll
+			}
+			list, err := p.mysqls.MyprojectV1beta1().MySqls(namespace).List(context.TODO(), meta_v1.ListOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to list mysqls. %+v", err)
+			}
+			if len(list.Items) == 0 {
+				fmt.Fprintln(os.Stderr, "No MySql instances found.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 8, 3, ' ', 0)
+			if allNamespaces {
+				fmt.Fprint(w, "NAMESPACE\t")
+			}
+			fmt.Fprintln(w, "NAME\tPHASE\tIMAGE\tEXTERNAL-ADDRESS\tAGE\tMESSAGE")
+			for i := range list.Items {
+				s := &list.Items[i]
+				if allNamespaces {
+					fmt.Fprintf(w, "%s\t", s.Namespace)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+					s.Name,
+					orNone(string(s.Status.Phase)),
+					image(s),
+					orNone(s.Status.ExternalAddress),
+					duration.HumanDuration(time.Since(s.CreationTimestamp.Time)),
+					s.Status.Message)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+// The image an instance runs. Instances that set neither an image nor a
+// version run the operator's default.
+func image(s *mysql.MySql) string {
+	switch {
+	case s.Spec.Image != "":
+		return s.Spec.Image
+	case s.Spec.Version != "":
+		return "mysql:" + s.Spec.Version
+	}
+	return "<default>"
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-mysql is a kubectl plugin for day-to-day work with the MySql objects
+// managed by the operator. Put it on the PATH and run it as `kubectl mysql`.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	mysqlversioned "github.com/tonya11en/mysql-operator/pkg/client/clientset/versioned"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// The port MySQL listens on in the pods the operator runs. The names it gives
+// the objects backing a MySql are shared through pkg/apis/myproject.
+const mysqlPort = 3306
+
+// command is one of the plugin's subcommands.
+type command struct {
+	name string
+	// Positional arguments, for the usage message.
+	args    string
+	summary string
+	// Registers the command's own flags, if it has any.
+	flags func(fs *flag.FlagSet)
+	run   func(p *plugin, args []string) error
+}
+
+func commands() []*command {
+	return []*command{
+		listCommand(),
+		infoCommand(),
+		shellCommand(),
+		portForwardCommand(),
+		backupCommand(),
+		pauseCommand(true),
+		pauseCommand(false),
+	}
+}
+
+// plugin holds the clients every command works with.
+type plugin struct {
+	namespace string
+	config    *rest.Config
+	clientset kubernetes.Interface
+	mysqls    mysqlversioned.Interface
+	dynamic   dynamic.Interface
+}
+
+// clientFlags are the kubectl flags the plugin understands, accepted by every
+// command.
+type clientFlags struct {
+	kubeconfig string
+	context    string
+	namespace  string
+}
+
+func (f *clientFlags) bind(fs *flag.FlagSet) {
+	fs.StringVar(&f.kubeconfig, "kubeconfig", "", "path to the kubeconfig file to use")
+	fs.StringVar(&f.context, "context", "", "kubeconfig context to use instead of the current one")
+	fs.StringVar(&f.namespace, "namespace", "", "namespace of the MySql, instead of the context's")
+	fs.StringVar(&f.namespace, "n", "", "shorthand for --namespace")
+}
+
+// Build the clients the same way kubectl does, honouring KUBECONFIG and the
+// namespace of the current context.
+func (f *clientFlags) plugin() (*plugin, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = f.kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: f.context}
+	overrides.Context.Namespace = f.namespace
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get k8s config. %+v", err)
+	}
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get namespace. %+v", err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get k8s client. %+v", err)
+	}
+	mysqls, err := mysqlversioned.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mysql clientset. %+v", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client. %+v", err)
+	}
+	return &plugin{
+		namespace: namespace,
+		config:    config,
+		clientset: clientset,
+		mysqls:    mysqls,
+		dynamic:   dynamicClient,
+	}, nil
+}
+
+// parseArgs parses flags given before, after or between the positional
+// arguments, as kubectl does. Everything after "--" is passed on as is.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var passthrough []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, passthrough = args[:i], args[i+1:]
+			break
+		}
+	}
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	return append(positional, passthrough...), nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: kubectl mysql <command> [flags]\n\nCommands:\n")
+	for _, c := range commands() {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun kubectl mysql <command> --help for the flags of a command.\n")
+}
+
+func main() {
+	if len(os.Args) < 2 || strings.HasPrefix(os.Args[1], "-") {
+		usage()
+		os.Exit(2)
+	}
+
+	var cmd *command
+	for _, c := range commands() {
+		if c.name == os.Args[1] {
+			cmd = c
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("kubectl mysql "+cmd.name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s\n\nUsage: kubectl mysql %s %s [flags]\n\nFlags:\n", cmd.summary, cmd.name, cmd.args)
+		fs.PrintDefaults()
+	}
+	var clients clientFlags
+	clients.bind(fs)
+	if cmd.flags != nil {
+		cmd.flags(fs)
+	}
+	args, err := parseArgs(fs, os.Args[2:])
+	if err != nil {
+		os.Exit(2)
+	}
+
+	p, err := clients.plugin()
+	if err == nil {
+		err = cmd.run(p, args)
+	}
+	if code, ok := err.(exitCode); ok {
+		os.Exit(int(code))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// exitCode is returned by a command that ran a program, such as the mysql
+// client, whose exit status becomes the plugin's.
+type exitCode int
+
+func (c exitCode) Error() string {
+	return fmt.Sprintf("exit status %d", int(c))
+}
+
+// Returns the single name a command acting on one MySql was given.
+func nameArg(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("expected the name of a MySql, got %d arguments", len(args))
+	}
+	return args[0], nil
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// pause stops the operator from reconciling an instance, resume lets it carry
+// on; see spec.paused.
+func pauseCommand(paused bool) *command {
+	name, summary := "pause", "Stop the operator from changing a MySql instance"
+	if !paused {
+		name, summary = "resume", "Let the operator manage a paused MySql instance again"
+	}
+	return &command{
+		name:    name,
+		args:    "<name>",
+		summary: summary,
+		run: func(p *plugin, args []string) error {
+			instance, err := nameArg(args)
+			if err != nil {
+				return err
+			}
+			patch := []byte(fmt.Sprintf(`{"spec":{"paused":%t}}`, paused))
+			_, err = p.mysqls.MyprojectV1beta1().MySqls(p.namespace).Patch(context.TODO(), instance, types.MergePatchType, patch, meta_v1.PatchOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to %s mysql %s. %+v", name, instance, err)
+			}
+			fmt.Printf("mysql/%s %sd\n", instance, name)
+			return nil
+		},
+	}
+}
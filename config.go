@@ -0,0 +1,398 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Feature toggles understood by the operator, with their default values.
+const (
+	// Record the phase of each instance in its status.
+	featureStatusUpdates = "StatusUpdates"
+)
+
+var defaultFeatures = map[string]bool{
+	featureStatusUpdates: true,
+}
+
+// operatorConfig is the runtime configuration of the operator. It is read from
+// an optional YAML file given with --config, and any flag set on the command
+// line takes precedence over the file.
+type operatorConfig struct {
+	// Namespaces to watch for MySql objects. An empty string means all of them.
+	Namespaces []string `json:"namespaces"`
+	// How often every MySql is reconciled even if nothing changed.
+	ResyncPeriod duration `json:"resyncPeriod"`
+	// Number of MySql objects reconciled concurrently.
+	Workers int `json:"workers"`
+	// How often and for how long to poll for the CRD to become established.
+	CRDPollInterval duration `json:"crdPollInterval"`
+	CRDTimeout      duration `json:"crdTimeout"`
+
+	HTTPAddr       string               `json:"httpAddr"`
+	Log            logConfig            `json:"log"`
+	LeaderElection leaderElectionConfig `json:"leaderElection"`
+	Webhook        webhookConfig        `json:"webhook"`
+	NetworkPolicy  networkPolicyConfig  `json:"networkPolicy"`
+	Defaults       instanceDefaults     `json:"defaults"`
+	Features       map[string]bool      `json:"features"`
+}
+
+// Repository of the image run for a MySql that sets spec.version.
+const mysqlImageRepository = "mysql"
+
+// instanceDefaults fill in fields a MySql leaves empty.
+type instanceDefaults struct {
+	Image   string `json:"image"`
+	Storage string `json:"storage"`
+	// The class of the snapshot the Snapshot deletion policy takes. Empty
+	// uses the cluster's default VolumeSnapshotClass.
+	VolumeSnapshotClass string `json:"volumeSnapshotClass"`
+}
+
+// The image an instance runs. An explicit image wins over a version.
+func (d instanceDefaults) image(s *mysql.MySql) string {
+	if s.Spec.Image != "" {
+		return s.Spec.Image
+	}
+	if s.Spec.Version != "" {
+		return mysqlImageRepository + ":" + s.Spec.Version
+	}
+	return d.Image
+}
+
+// The size of the volume an instance asks for.
+func (d instanceDefaults) storage(s *mysql.MySql) (resource.Quantity, error) {
+	if s.Spec.Storage.Size != nil {
+		return *s.Spec.Storage.Size, nil
+	}
+	return resource.ParseQuantity(d.Storage)
+}
+
+// duration is a time.Duration written as a string such as "30s" in the
+// config file.
+type duration struct {
+	time.Duration
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("durations must be strings such as \"30s\". %+v", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func defaultConfig() *operatorConfig {
+	return &operatorConfig{
+		Namespaces:      []string{v1.NamespaceAll},
+		ResyncPeriod:    duration{5 * time.Minute},
+		Workers:         2,
+		CRDPollInterval: duration{500 * time.Millisecond},
+		CRDTimeout:      duration{60 * time.Second},
+		HTTPAddr:        ":8080",
+		Log: logConfig{
+			Format: "json",
+			Level:  "info",
+		},
+		LeaderElection: leaderElectionConfig{
+			Enabled:       true,
+			LeaseDuration: duration{15 * time.Second},
+			RenewDeadline: duration{10 * time.Second},
+			RetryPeriod:   duration{2 * time.Second},
+		},
+		Webhook: webhookConfig{
+			Enabled:        true,
+			Port:           9443,
+			ServiceName:    "mysql-operator-webhook",
+			CertSecretName: "mysql-operator-webhook-cert",
+		},
+		NetworkPolicy: networkPolicyConfig{
+			OperatorSelector: "app=mysql-operator",
+		},
+		Defaults: instanceDefaults{
+			Image:   "mysql:5.6",
+			Storage: "20G",
+		},
+		Features: map[string]bool{},
+	}
+}
+
+// stringList is a comma separated flag value. Setting it replaces the whole
+// list rather than appending to it.
+type stringList struct {
+	list *[]string
+}
+
+func (s stringList) String() string {
+	if s.list == nil {
+		return ""
+	}
+	return strings.Join(*s.list, ",")
+}
+
+func (s stringList) Set(value string) error {
+	*s.list = strings.Split(value, ",")
+	return nil
+}
+
+// featureList is a flag value of the form "Name=true,Other=false".
+type featureList struct {
+	features *map[string]bool
+}
+
+func (f featureList) String() string {
+	if f.features == nil {
+		return ""
+	}
+	var pairs []string
+	for name, enabled := range *f.features {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", name, enabled))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (f featureList) Set(value string) error {
+	features := map[string]bool{}
+	for _, pair := range strings.Split(value, ",") {
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		enabled := true
+		if len(parts) == 2 {
+			var err error
+			if enabled, err = strconv.ParseBool(parts[1]); err != nil {
+				return fmt.Errorf("invalid value for feature %s: %q", parts[0], parts[1])
+			}
+		}
+		features[parts[0]] = enabled
+	}
+	*f.features = features
+	return nil
+}
+
+// bindFlags registers a flag for every config field on fs, using the current
+// values of cfg as the defaults.
+func (cfg *operatorConfig) bindFlags(fs *flag.FlagSet) {
+	fs.Var(stringList{&cfg.Namespaces}, "namespaces", "comma separated namespaces to watch for MySql objects (empty watches all namespaces)")
+	fs.DurationVar(&cfg.ResyncPeriod.Duration, "resync-period", cfg.ResyncPeriod.Duration, "how often every MySql is reconciled even if nothing changed")
+	fs.IntVar(&cfg.Workers, "workers", cfg.Workers, "number of MySql objects reconciled concurrently")
+	fs.DurationVar(&cfg.CRDPollInterval.Duration, "crd-poll-interval", cfg.CRDPollInterval.Duration, "how often to check whether the MySql CRD is established")
+	fs.DurationVar(&cfg.CRDTimeout.Duration, "crd-timeout", cfg.CRDTimeout.Duration, "how long to wait for the MySql CRD to be established")
+	fs.StringVar(&cfg.HTTPAddr, "http-addr", cfg.HTTPAddr, "address to serve /metrics, /healthz and /readyz on")
+	fs.StringVar(&cfg.Log.Format, "log-format", cfg.Log.Format, "log format, either json or console")
+	fs.StringVar(&cfg.Log.Level, "log-level", cfg.Log.Level, "minimum level to log: debug, info, warn or error")
+	fs.IntVar(&cfg.Log.Verbosity, "v", cfg.Log.Verbosity, "log verbosity; any value above zero is the same as --log-level=debug")
+
+	fs.BoolVar(&cfg.LeaderElection.Enabled, "leader-elect", cfg.LeaderElection.Enabled, "only reconcile while holding the leader lease, so several replicas can run")
+	fs.StringVar(&cfg.LeaderElection.Namespace, "leader-elect-namespace", cfg.LeaderElection.Namespace, "namespace of the leader lease (defaults to POD_NAMESPACE, then \"default\")")
+	fs.DurationVar(&cfg.LeaderElection.LeaseDuration.Duration, "leader-elect-lease-duration", cfg.LeaderElection.LeaseDuration.Duration, "how long standby replicas wait before taking over an unrenewed lease")
+	fs.DurationVar(&cfg.LeaderElection.RenewDeadline.Duration, "leader-elect-renew-deadline", cfg.LeaderElection.RenewDeadline.Duration, "how long the leader keeps retrying to renew the lease before giving it up")
+	fs.DurationVar(&cfg.LeaderElection.RetryPeriod.Duration, "leader-elect-retry-period", cfg.LeaderElection.RetryPeriod.Duration, "how long replicas wait between attempts to acquire or renew the lease")
+
+	fs.BoolVar(&cfg.Webhook.Enabled, "webhook", cfg.Webhook.Enabled, "serve the admission webhooks and register them with the API server")
+	fs.IntVar(&cfg.Webhook.Port, "webhook-port", cfg.Webhook.Port, "port to serve the admission webhooks on")
+	fs.StringVar(&cfg.Webhook.ServiceName, "webhook-service-name", cfg.Webhook.ServiceName, "name of the Service the API server reaches the webhooks through")
+	fs.StringVar(&cfg.Webhook.ServiceNamespace, "webhook-service-namespace", cfg.Webhook.ServiceNamespace, "namespace of the webhook Service (defaults to POD_NAMESPACE, then \"default\")")
+	fs.StringVar(&cfg.Webhook.CertSecretName, "webhook-cert-secret", cfg.Webhook.CertSecretName, "name of the Secret holding the webhook serving certificate")
+	fs.Var(stringList{&cfg.Webhook.AllowedImages}, "webhook-allowed-images", "comma separated image patterns MySql objects may use, such as mysql:* (empty allows any image)")
+
+	fs.StringVar(&cfg.NetworkPolicy.OperatorSelector, "network-policy-operator-selector", cfg.NetworkPolicy.OperatorSelector, "label selector matching the operator's pods, which NetworkPolicies of MySql objects let in")
+	fs.StringVar(&cfg.NetworkPolicy.OperatorNamespaceSelector, "network-policy-operator-namespace-selector", cfg.NetworkPolicy.OperatorNamespaceSelector, "label selector matching the namespaces the operator's pods run in (empty matches the operator's namespace by its kubernetes.io/metadata.name label)")
+	fs.StringVar(&cfg.NetworkPolicy.OperatorNamespace, "network-policy-operator-namespace", cfg.NetworkPolicy.OperatorNamespace, "namespace the operator runs in, used without a namespace selector (defaults to POD_NAMESPACE, then \"default\")")
+
+	fs.StringVar(&cfg.Defaults.Image, "default-image", cfg.Defaults.Image, "image used for MySql objects that do not set one")
+	fs.StringVar(&cfg.Defaults.Storage, "default-storage", cfg.Defaults.Storage, "size of the volume claimed for each MySql")
+	fs.StringVar(&cfg.Defaults.VolumeSnapshotClass, "default-volume-snapshot-class", cfg.Defaults.VolumeSnapshotClass, "VolumeSnapshotClass of the final snapshot taken by the Snapshot deletion policy (empty uses the default class)")
+	fs.Var(featureList{&cfg.Features}, "feature-gates", "comma separated list of Feature=true|false pairs")
+}
+
+// Prefix of the environment variables that set operator flags. The variable
+// for --leader-elect-namespace is MYSQL_OPERATOR_LEADER_ELECT_NAMESPACE.
+const envPrefix = "MYSQL_OPERATOR_"
+
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// loadConfig parses the command line, reads the config file named by
+// --config if there is one, and validates the result. Environment variables
+// override values from the file, and flags given on the command line override
+// both.
+func loadConfig(fs *flag.FlagSet, args []string) (*operatorConfig, error) {
+	cfg := defaultConfig()
+	configPath := fs.String("config", "", "path to a YAML file holding the operator configuration")
+	cfg.bindFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// Remember what was given in the environment and on the command line,
+	// the latter winning, so it can be put back after the file is read.
+	overrides := map[string]string{}
+	fs.VisitAll(func(f *flag.Flag) {
+		if value, ok := os.LookupEnv(envName(f.Name)); ok {
+			overrides[f.Name] = value
+		}
+	})
+	fs.Visit(func(f *flag.Flag) {
+		overrides[f.Name] = f.Value.String()
+	})
+	override := func() error {
+		for name, value := range overrides {
+			if err := fs.Set(name, value); err != nil {
+				return fmt.Errorf("invalid value %q for %s: %v", value, name, err)
+			}
+		}
+		return nil
+	}
+	if err := override(); err != nil {
+		return nil, err
+	}
+
+	if *configPath != "" {
+		data, err := ioutil.ReadFile(*configPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file. %+v", err)
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s. %+v", *configPath, err)
+		}
+		if err := override(); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, enabled := range defaultFeatures {
+		if _, ok := cfg.Features[name]; !ok {
+			cfg.Features[name] = enabled
+		}
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %v", err)
+	}
+	return cfg, nil
+}
+
+// validate reports every problem with the config at once.
+func (cfg *operatorConfig) validate() error {
+	var errs []error
+
+	if len(cfg.Namespaces) == 0 {
+		errs = append(errs, fmt.Errorf("namespaces: at least one namespace is required, use \"\" for all namespaces"))
+	}
+	seen := map[string]bool{}
+	for _, ns := range cfg.Namespaces {
+		if ns == v1.NamespaceAll && len(cfg.Namespaces) > 1 {
+			errs = append(errs, fmt.Errorf("namespaces: all namespaces (\"\") cannot be combined with specific namespaces"))
+		}
+		if ns != v1.NamespaceAll {
+			for _, msg := range validation.IsDNS1123Label(ns) {
+				errs = append(errs, fmt.Errorf("namespaces: %q is not a valid namespace: %s", ns, msg))
+			}
+		}
+		if seen[ns] {
+			errs = append(errs, fmt.Errorf("namespaces: %q is listed more than once", ns))
+		}
+		seen[ns] = true
+	}
+
+	if cfg.ResyncPeriod.Duration < 0 {
+		errs = append(errs, fmt.Errorf("resyncPeriod: must not be negative, got %v", cfg.ResyncPeriod))
+	}
+	if cfg.Workers < 1 {
+		errs = append(errs, fmt.Errorf("workers: must be at least 1, got %d", cfg.Workers))
+	}
+	if cfg.CRDPollInterval.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("crdPollInterval: must be positive, got %v", cfg.CRDPollInterval))
+	}
+	if cfg.CRDTimeout.Duration < cfg.CRDPollInterval.Duration {
+		errs = append(errs, fmt.Errorf("crdTimeout: must be at least crdPollInterval (%v), got %v", cfg.CRDPollInterval, cfg.CRDTimeout))
+	}
+	if cfg.HTTPAddr == "" {
+		errs = append(errs, fmt.Errorf("httpAddr: must not be empty"))
+	}
+	errs = append(errs, cfg.Log.validate()...)
+
+	le := cfg.LeaderElection
+	if le.Enabled {
+		if le.LeaseDuration.Duration <= le.RenewDeadline.Duration {
+			errs = append(errs, fmt.Errorf("leaderElection: leaseDuration (%v) must be greater than renewDeadline (%v)", le.LeaseDuration, le.RenewDeadline))
+		}
+		if le.RetryPeriod.Duration <= 0 || le.RenewDeadline.Duration <= le.RetryPeriod.Duration {
+			errs = append(errs, fmt.Errorf("leaderElection: renewDeadline (%v) must be greater than retryPeriod (%v), which must be positive", le.RenewDeadline, le.RetryPeriod))
+		}
+	}
+
+	errs = append(errs, cfg.Webhook.validate()...)
+	errs = append(errs, cfg.NetworkPolicy.validate()...)
+
+	if cfg.Defaults.Image == "" {
+		errs = append(errs, fmt.Errorf("defaults.image: must not be empty"))
+	}
+	if q, err := resource.ParseQuantity(cfg.Defaults.Storage); err != nil {
+		errs = append(errs, fmt.Errorf("defaults.storage: %q is not a valid quantity: %v", cfg.Defaults.Storage, err))
+	} else if q.Sign() <= 0 {
+		errs = append(errs, fmt.Errorf("defaults.storage: must be positive, got %s", cfg.Defaults.Storage))
+	}
+	if class := cfg.Defaults.VolumeSnapshotClass; class != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(class) {
+			errs = append(errs, fmt.Errorf("defaults.volumeSnapshotClass: %q is not a valid name: %s", class, msg))
+		}
+	}
+
+	for name := range cfg.Features {
+		if _, ok := defaultFeatures[name]; !ok {
+			errs = append(errs, fmt.Errorf("features: unknown feature %q", name))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// enabled reports whether a feature toggle is on.
+func (cfg *operatorConfig) enabled(feature string) bool {
+	return cfg.Features[feature]
+}
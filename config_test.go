@@ -0,0 +1,340 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// main binds the operator's flags on flag.CommandLine, next to whatever the
+// linked libraries registered there. A flag defined twice panics. The
+// libraries' flags are copied to a new set so the test can run more than
+// once.
+func TestLoadConfigCommandLine(t *testing.T) {
+	fs := flag.NewFlagSet(flag.CommandLine.Name(), flag.ContinueOnError)
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		fs.Var(f.Value, f.Name, f.Usage)
+	})
+	cfg, err := loadConfig(fs, []string{"--v=2", "--workers=3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Log.Verbosity != 2 || cfg.Workers != 3 {
+		t.Errorf("got verbosity %d and workers %d, want 2 and 3", cfg.Log.Verbosity, cfg.Workers)
+	}
+}
+
+// Parse args on a fresh flag set with env set in the environment.
+func loadTestConfig(t *testing.T, env map[string]string, args ...string) (*operatorConfig, error) {
+	for name, value := range env {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+	return loadConfig(flag.NewFlagSet("test", flag.ContinueOnError), args)
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "mysql-operator-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := writeConfigFile(t, "workers: 4\nresyncPeriod: 1m\nnamespaces: [team-a]\n")
+	defer os.Remove(path)
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		args        []string
+		wantWorkers int
+	}{
+		{
+			name:        "defaults",
+			wantWorkers: 2,
+		},
+		{
+			name:        "file",
+			args:        []string{"--config", path},
+			wantWorkers: 4,
+		},
+		{
+			name:        "env over file",
+			env:         map[string]string{"MYSQL_OPERATOR_WORKERS": "5"},
+			args:        []string{"--config", path},
+			wantWorkers: 5,
+		},
+		{
+			name:        "flag over env and file",
+			env:         map[string]string{"MYSQL_OPERATOR_WORKERS": "5"},
+			args:        []string{"--config", path, "--workers=6"},
+			wantWorkers: 6,
+		},
+		{
+			name:        "config path from env",
+			env:         map[string]string{"MYSQL_OPERATOR_CONFIG": path},
+			wantWorkers: 4,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := loadTestConfig(t, test.env, test.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Workers != test.wantWorkers {
+				t.Errorf("got %d workers, want %d", cfg.Workers, test.wantWorkers)
+			}
+		})
+	}
+
+	// Values only the file sets survive the overrides of other fields.
+	cfg, err := loadTestConfig(t, map[string]string{"MYSQL_OPERATOR_WORKERS": "5"}, "--config", path, "--log-level=debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ResyncPeriod.Duration != time.Minute || strings.Join(cfg.Namespaces, ",") != "team-a" || cfg.Log.Level != "debug" {
+		t.Errorf("got resync period %v, namespaces %q and log level %q, want 1m0s, team-a and debug", cfg.ResyncPeriod, cfg.Namespaces, cfg.Log.Level)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{
+			name: "unknown flag",
+			args: []string{"--no-such-flag"},
+			want: "flag provided but not defined",
+		},
+		{
+			name: "bad env value",
+			env:  map[string]string{"MYSQL_OPERATOR_WORKERS": "many"},
+			want: `invalid value "many" for workers`,
+		},
+		{
+			name: "missing file",
+			args: []string{"--config", "/does/not/exist"},
+			want: "failed to read config file",
+		},
+		{
+			name: "invalid value",
+			args: []string{"--workers=0"},
+			want: "workers: must be at least 1, got 0",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := loadTestConfig(t, test.env, test.args...)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want one containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*operatorConfig)
+		want   string
+	}{
+		{
+			name:   "defaults",
+			modify: func(cfg *operatorConfig) {},
+		},
+		{
+			name:   "no namespaces",
+			modify: func(cfg *operatorConfig) { cfg.Namespaces = nil },
+			want:   "namespaces: at least one namespace is required",
+		},
+		{
+			name:   "all namespaces and a specific one",
+			modify: func(cfg *operatorConfig) { cfg.Namespaces = []string{"", "team-a"} },
+			want:   "all namespaces (\"\") cannot be combined",
+		},
+		{
+			name:   "invalid namespace",
+			modify: func(cfg *operatorConfig) { cfg.Namespaces = []string{"Team_A"} },
+			want:   "namespaces: \"Team_A\" is not a valid namespace",
+		},
+		{
+			name:   "duplicate namespace",
+			modify: func(cfg *operatorConfig) { cfg.Namespaces = []string{"team-a", "team-a"} },
+			want:   "namespaces: \"team-a\" is listed more than once",
+		},
+		{
+			name:   "negative resync period",
+			modify: func(cfg *operatorConfig) { cfg.ResyncPeriod.Duration = -time.Second },
+			want:   "resyncPeriod: must not be negative",
+		},
+		{
+			name:   "no workers",
+			modify: func(cfg *operatorConfig) { cfg.Workers = 0 },
+			want:   "workers: must be at least 1",
+		},
+		{
+			name:   "zero crd poll interval",
+			modify: func(cfg *operatorConfig) { cfg.CRDPollInterval.Duration = 0 },
+			want:   "crdPollInterval: must be positive",
+		},
+		{
+			name:   "crd timeout below poll interval",
+			modify: func(cfg *operatorConfig) { cfg.CRDTimeout.Duration = time.Millisecond },
+			want:   "crdTimeout: must be at least crdPollInterval",
+		},
+		{
+			name:   "no http address",
+			modify: func(cfg *operatorConfig) { cfg.HTTPAddr = "" },
+			want:   "httpAddr: must not be empty",
+		},
+		{
+			name:   "log format",
+			modify: func(cfg *operatorConfig) { cfg.Log.Format = "xml" },
+			want:   "log.format: must be \"json\" or \"console\"",
+		},
+		{
+			name:   "log level",
+			modify: func(cfg *operatorConfig) { cfg.Log.Level = "loud" },
+			want:   "log.level:",
+		},
+		{
+			name:   "negative verbosity",
+			modify: func(cfg *operatorConfig) { cfg.Log.Verbosity = -1 },
+			want:   "log.v: must not be negative",
+		},
+		{
+			name:   "lease shorter than renew deadline",
+			modify: func(cfg *operatorConfig) { cfg.LeaderElection.LeaseDuration.Duration = 5 * time.Second },
+			want:   "leaderElection: leaseDuration (5s) must be greater than renewDeadline (10s)",
+		},
+		{
+			name:   "renew deadline shorter than retry period",
+			modify: func(cfg *operatorConfig) { cfg.LeaderElection.RetryPeriod.Duration = 10 * time.Second },
+			want:   "leaderElection: renewDeadline (10s) must be greater than retryPeriod (10s)",
+		},
+		{
+			name: "leader election disabled",
+			modify: func(cfg *operatorConfig) {
+				cfg.LeaderElection.Enabled = false
+				cfg.LeaderElection.LeaseDuration.Duration = 0
+			},
+		},
+		{
+			name:   "webhook port",
+			modify: func(cfg *operatorConfig) { cfg.Webhook.Port = 70000 },
+			want:   "webhook.port: must be between 1 and 65535",
+		},
+		{
+			name:   "webhook service name",
+			modify: func(cfg *operatorConfig) { cfg.Webhook.ServiceName = "" },
+			want:   "webhook.serviceName: must not be empty",
+		},
+		{
+			name:   "webhook cert secret",
+			modify: func(cfg *operatorConfig) { cfg.Webhook.CertSecretName = "" },
+			want:   "webhook.certSecretName: must not be empty",
+		},
+		{
+			name:   "allowed image pattern",
+			modify: func(cfg *operatorConfig) { cfg.Webhook.AllowedImages = []string{"mysql:["} },
+			want:   "webhook.allowedImages: \"mysql:[\" is not a valid pattern",
+		},
+		{
+			name: "webhook disabled",
+			modify: func(cfg *operatorConfig) {
+				cfg.Webhook.Enabled = false
+				cfg.Webhook.Port = 0
+			},
+		},
+		{
+			name:   "no operator selector",
+			modify: func(cfg *operatorConfig) { cfg.NetworkPolicy.OperatorSelector = "" },
+			want:   "networkPolicy.operatorSelector: must not be empty",
+		},
+		{
+			name:   "bad operator selector",
+			modify: func(cfg *operatorConfig) { cfg.NetworkPolicy.OperatorSelector = "app in" },
+			want:   "networkPolicy.operatorSelector:",
+		},
+		{
+			name:   "bad operator namespace selector",
+			modify: func(cfg *operatorConfig) { cfg.NetworkPolicy.OperatorNamespaceSelector = "team=a=b" },
+			want:   "networkPolicy.operatorNamespaceSelector:",
+		},
+		{
+			name:   "bad operator namespace",
+			modify: func(cfg *operatorConfig) { cfg.NetworkPolicy.OperatorNamespace = "Ops" },
+			want:   "networkPolicy.operatorNamespace: \"Ops\" is not a valid namespace",
+		},
+		{
+			name:   "no default image",
+			modify: func(cfg *operatorConfig) { cfg.Defaults.Image = "" },
+			want:   "defaults.image: must not be empty",
+		},
+		{
+			name:   "bad default storage",
+			modify: func(cfg *operatorConfig) { cfg.Defaults.Storage = "lots" },
+			want:   "defaults.storage: \"lots\" is not a valid quantity",
+		},
+		{
+			name:   "bad default snapshot class",
+			modify: func(cfg *operatorConfig) { cfg.Defaults.VolumeSnapshotClass = "Fast_SSD" },
+			want:   "defaults.volumeSnapshotClass: \"Fast_SSD\" is not a valid name",
+		},
+		{
+			name:   "zero default storage",
+			modify: func(cfg *operatorConfig) { cfg.Defaults.Storage = "0" },
+			want:   "defaults.storage: must be positive",
+		},
+		{
+			name:   "unknown feature",
+			modify: func(cfg *operatorConfig) { cfg.Features["Teleport"] = true },
+			want:   "features: unknown feature \"Teleport\"",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			test.modify(cfg)
+			err := cfg.validate()
+			if test.want == "" {
+				if err != nil {
+					t.Errorf("got error %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want one containing %q", err, test.want)
+			}
+		})
+	}
+}
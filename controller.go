@@ -23,61 +23,162 @@ which have the same license.
 package main
 
 import (
+	"context"
 	"fmt"
-
-	opkit "github.com/rook/operator-kit"
-	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1alpha1"
-	mysqlclient "github.com/tonya11en/mysql-operator/pkg/client/clientset/versioned/typed/myproject/v1alpha1"
-	"k8s.io/api/apps/v1beta2"
+	"strings"
+	"time"
+
+	"github.com/tonya11en/mysql-operator/pkg/apis/myproject"
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	mysqlversioned "github.com/tonya11en/mysql-operator/pkg/client/clientset/versioned"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 )
 
-// MySqlController represents a controller object for mysql custom resources
+// MySqlController represents a controller object for mysql custom resources.
+// It only talks to the API server through the client and recorder
+// interfaces it is given, so it can be run against fake clientsets.
 type MySqlController struct {
-	context        *opkit.Context
-	mySqlClientset mysqlclient.MyprojectV1alpha1Interface
+	clientset      kubernetes.Interface
+	mySqlClientset mysqlversioned.Interface
+	dynamicClient  dynamic.Interface
+	caches         map[string]*namespaceCache
+	queue          workqueue.RateLimitingInterface
+	health         *healthChecker
+	phases         *phaseTracker
+	config         *operatorConfig
+	recorder       record.EventRecorder
 }
 
 // Creates a controller watching for mysql custom resources.
-func newMySqlController(context *opkit.Context, mySqlClientset mysqlclient.MyprojectV1alpha1Interface) *MySqlController {
+func newMySqlController(clientset kubernetes.Interface, mySqlClientset mysqlversioned.Interface, dynamicClient dynamic.Interface, recorder record.EventRecorder, health *healthChecker, config *operatorConfig) *MySqlController {
 	return &MySqlController{
-		context:        context,
+		clientset:      clientset,
 		mySqlClientset: mySqlClientset,
+		dynamicClient:  dynamicClient,
+		caches:         map[string]*namespaceCache{},
+		queue:          workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		health:         health,
+		phases:         newPhaseTracker(),
+		config:         config,
+		recorder:       recorder,
 	}
 }
 
-// Watch watches for instances of MySql custom resources and acts on them
-func (c *MySqlController) StartWatch(namespace string, stopCh chan struct{}) error {
-	fmt.Println("Starting watch on the mysql resource")
+// Watch watches for instances of MySql custom resources in the given
+// namespaces and acts on them. The workers start once the caches have synced.
+func (c *MySqlController) StartWatch(namespaces []string, stopCh chan struct{}) error {
+	var synced []cache.InformerSynced
+	for _, namespace := range namespaces {
+		log.Info("starting watch on the mysql resource", zap.String("namespace", namespace))
+		n := c.addNamespace(namespace)
+		c.health.watch(namespace, n, c.config.ResyncPeriod.Duration)
+		n.start(stopCh)
+		synced = append(synced, n.synced...)
+	}
+
+	go func() {
+		if !cache.WaitForCacheSync(stopCh, synced...) {
+			log.Error("stopped before the caches synced")
+			return
+		}
+		log.Info("caches synced, starting workers")
+
+		for i := 0; i < c.config.Workers; i++ {
+			id := i
+			go wait.Until(func() { c.runWorker(id) }, time.Second, stopCh)
+		}
+		c.health.setWorkersStarted()
+	}()
+
+	go func() {
+		<-stopCh
+		c.health.watchesStopped()
+		c.queue.ShutDown()
+	}()
+	return nil
+}
 
-	resourceHandlers := cache.ResourceEventHandlerFuncs{
+// Set up the caches of a namespace and queue its MySqls on every change. The
+// informers are not started.
+func (c *MySqlController) addNamespace(namespace string) *namespaceCache {
+	n := newNamespaceCache(c.clientset, c.mySqlClientset, namespace, c.config.ResyncPeriod.Duration)
+	n.mySqlInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    c.onAdd,
 		UpdateFunc: c.onUpdate,
 		DeleteFunc: c.onDelete,
+	})
+	for _, informer := range n.owned {
+		informer.AddEventHandler(c.ownedHandlers())
 	}
-	restClient := c.mySqlClientset.RESTClient()
-	watcher := opkit.NewWatcher(mysql.MySqlResource, namespace, resourceHandlers, restClient)
-	go watcher.Watch(&mysql.MySql{}, stopCh)
-	return nil
+	c.caches[namespace] = n
+	return n
 }
 
-// Create a pod spec. Note that this is specific to the example found here:
-// https://kubernetes.io/docs/tasks/run-application/run-single-instance-stateful-application/
-func (c *MySqlController) makePodSpec(objName string, ctrName string, ctrImage string, port int32, podGroup string, envVars map[string]string) *v1.PodTemplateSpec {
-	var env []v1.EnvVar
-	for k, v := range envVars {
-		env = append(env, v1.EnvVar{Name: k, Value: v})
+// runWorker processes keys from the queue until it is shut down.
+func (c *MySqlController) runWorker(id int) {
+	for c.processNextItem(id) {
 	}
+}
+
+func (c *MySqlController) processNextItem(id int) bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	c.health.workerBusy(id)
+	defer c.health.workerIdle(id)
+
+	start := time.Now()
+	err := c.reconcile(key.(string))
+	observeReconcile(start, err)
+	if err == errSnapshotPending {
+		log.Debug("waiting for volume snapshot, requeueing", zap.String("key", key.(string)))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	if err != nil {
+		log.Error("failed to reconcile mysql, requeueing", zap.String("key", key.(string)), zap.Error(err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+
+	c.queue.Forget(key)
+	return true
+}
+
+// The labels selecting the pods of the MySql called name. The instance label
+// keeps the Services and Deployments of two MySqls in a namespace from
+// picking up each other's pods.
+func podSelector(name string) map[string]string {
+	return map[string]string{"app": "mysql", myproject.InstanceLabel: name}
+}
 
-	volumeName := "mysql-persistent-storage"
+// Create a pod spec. Note that this is specific to the example found here:
+// https://kubernetes.io/docs/tasks/run-application/run-single-instance-stateful-application/
+func (c *MySqlController) makePodSpec(objName string, ctrName string, ctrImage string, port int32, podGroup string, env []v1.EnvVar, tls *podTLS) *v1.PodTemplateSpec {
+	volumeName := dataVolumeName
 	podSpec := &v1.PodTemplateSpec{
 		ObjectMeta: meta_v1.ObjectMeta{
 			Name:   objName,
-			Labels: map[string]string{"app": "mysql"},
+			Labels: podSelector(objName),
 		},
 		Spec: v1.PodSpec{
 			Containers: []v1.Container{
@@ -93,7 +194,7 @@ func (c *MySqlController) makePodSpec(objName string, ctrName string, ctrImage s
 					VolumeMounts: []v1.VolumeMount{
 						{
 							Name:      volumeName,
-							MountPath: "/var/lib/" + objName,
+							MountPath: dataMountPath,
 						},
 					},
 				},
@@ -103,170 +204,563 @@ func (c *MySqlController) makePodSpec(objName string, ctrName string, ctrImage s
 					Name: volumeName,
 					VolumeSource: v1.VolumeSource{
 						PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
-							ClaimName: objName + "-pv-claim",
+							ClaimName: getPvcName(objName),
 						},
 					},
 				},
 			},
 		},
 	}
+	if tls != nil {
+		tls.mount(podSpec)
+	}
 
 	return podSpec
 }
 
-// Create a service.
-func (c *MySqlController) makeService(name string, port int32) (*v1.Service, error) {
-	fmt.Println("Making svc")
-	coreV1Client := c.context.Clientset.CoreV1()
-	svc, err := coreV1Client.Services(v1.NamespaceDefault).Create(&v1.Service{
+// The Service in front of a MySql, forwarding every port in its spec to
+// targetPort. A ClusterIP Service is headless.
+func newService(s *mysql.MySql, targetPort int32, labels map[string]string) *v1.Service {
+	spec := s.Spec.Service
+	svc := &v1.Service{
 		ObjectMeta: meta_v1.ObjectMeta{
-			Name:   name,
-			Labels: map[string]string{"app": "mysql"},
+			Name:        s.Name,
+			Namespace:   s.Namespace,
+			Labels:      labels,
+			Annotations: managedAnnotations(spec.Annotations),
 		},
 		Spec: v1.ServiceSpec{
-			Selector: map[string]string{"app": "mysql"},
-			Ports: []v1.ServicePort{
-				{
-					Port: port,
-				},
-			},
-			ClusterIP: v1.ClusterIPNone,
+			Type:     serviceType(s),
+			Selector: podSelector(s.Name),
 		},
-	})
+	}
+	switch svc.Spec.Type {
+	case v1.ServiceTypeClusterIP:
+		svc.Spec.ClusterIP = v1.ClusterIPNone
+	case v1.ServiceTypeLoadBalancer:
+		svc.Spec.LoadBalancerSourceRanges = spec.LoadBalancerSourceRanges
+		fallthrough
+	default:
+		svc.Spec.ExternalTrafficPolicy = spec.ExternalTrafficPolicy
+		if svc.Spec.ExternalTrafficPolicy == "" {
+			svc.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyTypeCluster
+		}
+	}
 
-	if err != nil {
-		fmt.Println("failed to create service:", err)
+	ports := spec.Ports
+	if len(ports) == 0 {
+		ports = []mysql.ServicePort{{Port: targetPort}}
+	}
+	for _, p := range ports {
+		port := v1.ServicePort{
+			Name:       p.Name,
+			Port:       p.Port,
+			TargetPort: intstr.FromInt(int(targetPort)),
+		}
+		if svc.Spec.Type != v1.ServiceTypeClusterIP {
+			port.NodePort = p.NodePort
+		}
+		svc.Spec.Ports = append(svc.Spec.Ports, port)
+	}
+	return svc
+}
+
+func serviceType(s *mysql.MySql) v1.ServiceType {
+	if s.Spec.Service.Type == "" {
+		return v1.ServiceTypeClusterIP
+	}
+	return s.Spec.Service.Type
+}
+
+// The address a LoadBalancer Service was given by the cloud provider.
+func externalAddress(svc *v1.Service) string {
+	if svc.Spec.Type != v1.ServiceTypeLoadBalancer {
+		return ""
+	}
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			return ingress.IP
+		}
+		if ingress.Hostname != "" {
+			return ingress.Hostname
+		}
 	}
+	return ""
+}
+
+// Create a service.
+func (c *MySqlController) makeService(log *zap.Logger, svc *v1.Service) (*v1.Service, error) {
+	log.Debug("making service")
+	coreV1Client := c.clientset.CoreV1()
+	created, err := coreV1Client.Services(svc.Namespace).Create(context.TODO(), svc, meta_v1.CreateOptions{})
 
-	return svc, err
+	logCreate(log, "service", svc.Name, err)
+
+	return created, err
 }
 
-// Create a PVC. Note that this is specific to the example found here:
+// The PVC holding the data of a MySql. Note that this is specific to the
+// example found here:
 // https://kubernetes.io/docs/tasks/run-application/run-single-instance-stateful-application/
-func (c *MySqlController) makePVC(name string) (*v1.PersistentVolumeClaim, error) {
-	fmt.Println("Making pvc")
-	coreV1Client := c.context.Clientset.CoreV1()
-	pvc, err := coreV1Client.PersistentVolumeClaims(v1.NamespaceDefault).Create(&v1.PersistentVolumeClaim{
+func newPVC(namespace string, name string, storage resource.Quantity, storageClassName string, labels map[string]string) *v1.PersistentVolumeClaim {
+	claim := &v1.PersistentVolumeClaim{
 		ObjectMeta: meta_v1.ObjectMeta{
-			Name: getPvcName(name),
+			Name:      getPvcName(name),
+			Namespace: namespace,
+			Labels:    labels,
 		},
 		Spec: v1.PersistentVolumeClaimSpec{
 			AccessModes: []v1.PersistentVolumeAccessMode{"ReadWriteOnce"},
-			Resources: v1.ResourceRequirements{
+			Resources: v1.VolumeResourceRequirements{
 				Requests: v1.ResourceList{
-					"storage": resource.MustParse("20G"),
+					"storage": storage,
 				},
 			},
 		},
-	})
-
-	if err != nil {
-		fmt.Println("failed to create pvc:", err)
 	}
+	if storageClassName != "" {
+		claim.Spec.StorageClassName = &storageClassName
+	}
+	return claim
+}
+
+// Create a PVC.
+func (c *MySqlController) makePVC(log *zap.Logger, claim *v1.PersistentVolumeClaim) (*v1.PersistentVolumeClaim, error) {
+	log.Debug("making pvc")
+	coreV1Client := c.clientset.CoreV1()
+	pvc, err := coreV1Client.PersistentVolumeClaims(claim.Namespace).Create(context.TODO(), claim, meta_v1.CreateOptions{})
+
+	logCreate(log, "pvc", claim.Name, err)
 
 	return pvc, err
 }
 
-// Make a deployment. Note that this is specific to the example found here:
+// Grow the claim when the MySql asks for more storage than it has. The
+// validating webhook refuses to shrink it.
+func (c *MySqlController) resizePVC(log *zap.Logger, s *mysql.MySql, pvc *v1.PersistentVolumeClaim, storage resource.Quantity) error {
+	current := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+	if storage.Cmp(current) <= 0 {
+		return nil
+	}
+
+	pvc = pvc.DeepCopy()
+	if pvc.Spec.Resources.Requests == nil {
+		pvc.Spec.Resources.Requests = v1.ResourceList{}
+	}
+	pvc.Spec.Resources.Requests[v1.ResourceStorage] = storage
+	if _, err := c.clientset.CoreV1().PersistentVolumeClaims(pvc.Namespace).Update(context.TODO(), pvc, meta_v1.UpdateOptions{}); err != nil {
+		c.recorder.Eventf(s, v1.EventTypeWarning, reasonFailedResize, "Failed to resize PersistentVolumeClaim %s to %s: %v", pvc.Name, storage.String(), err)
+		return fmt.Errorf("failed to resize pvc. %+v", err)
+	}
+	log.Info("resized pvc", zap.String("pvc", pvc.Name), zap.String("from", current.String()), zap.String("to", storage.String()))
+	c.recorder.Eventf(s, v1.EventTypeNormal, reasonResized, "Resized PersistentVolumeClaim %s from %s to %s", pvc.Name, current.String(), storage.String())
+	return nil
+}
+
+// The root password of the server, read from a Secret when the MySql
+// references one.
+func rootPasswordEnv(s *mysql.MySql) v1.EnvVar {
+	env := v1.EnvVar{Name: "MYSQL_ROOT_PASSWORD"}
+	if ref := s.Spec.Credentials.RootPasswordSecretRef; ref != nil {
+		env.ValueFrom = &v1.EnvVarSource{SecretKeyRef: ref.DeepCopy()}
+	} else {
+		env.Value = s.Spec.Credentials.RootPassword
+	}
+	return env
+}
+
+// The deployment running a MySql. Note that this is specific to the example
+// found here:
 // https://kubernetes.io/docs/tasks/run-application/run-single-instance-stateful-application/
-func (c *MySqlController) makeDeployment(name string, podSpec v1.PodTemplateSpec) (*v1beta2.Deployment, error) {
-	fmt.Println("Making deployment")
-	appsClient := c.context.Clientset.AppsV1beta2()
-	deployment, err := appsClient.Deployments(v1.NamespaceDefault).Create(&v1beta2.Deployment{
+func newDeployment(namespace string, name string, podSpec v1.PodTemplateSpec, labels map[string]string) *appsv1.Deployment {
+	return &appsv1.Deployment{
 		ObjectMeta: meta_v1.ObjectMeta{
-			Name: name,
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
 		},
-		Spec: v1beta2.DeploymentSpec{
+		Spec: appsv1.DeploymentSpec{
 			Template: podSpec,
 			Selector: &meta_v1.LabelSelector{
-				MatchLabels: map[string]string{"app": "mysql"},
+				MatchLabels: podSelector(name),
 			},
-			Strategy: v1beta2.DeploymentStrategy{
-				Type: v1beta2.RecreateDeploymentStrategyType,
+			Strategy: appsv1.DeploymentStrategy{
+				Type: appsv1.RecreateDeploymentStrategyType,
 			},
 		},
-	})
+	}
+}
 
-	if err != nil {
-		fmt.Println("failed to create deployment:", err)
+// Make a deployment.
+func (c *MySqlController) makeDeployment(log *zap.Logger, deployment *appsv1.Deployment) (*appsv1.Deployment, error) {
+	log.Debug("making deployment")
+	appsClient := c.clientset.AppsV1()
+	created, err := appsClient.Deployments(deployment.Namespace).Create(context.TODO(), deployment, meta_v1.CreateOptions{})
+
+	logCreate(log, "deployment", deployment.Name, err)
+
+	return created, err
+}
+
+// Log the outcome of creating one of the objects backing an instance. An
+// object that already exists is expected on every reconcile after the first.
+func logCreate(log *zap.Logger, kind string, name string, err error) {
+	switch {
+	case err == nil:
+		log.Info("created "+kind, zap.String(kind, name))
+	case errors.IsAlreadyExists(err):
+		log.Debug(kind+" already exists", zap.String(kind, name))
+	default:
+		log.Error("failed to create "+kind, zap.String(kind, name), zap.Error(err))
 	}
+}
 
-	return deployment, err
+// Record an Event on the MySql for the outcome of creating one of its objects.
+func (c *MySqlController) recordCreate(s *mysql.MySql, kind string, name string, err error) {
+	switch {
+	case err == nil:
+		c.recorder.Eventf(s, v1.EventTypeNormal, reasonCreated, "Created %s %s", kind, name)
+	case errors.IsAlreadyExists(err):
+	default:
+		c.recorder.Eventf(s, v1.EventTypeWarning, reasonFailedCreate, "Failed to create %s %s: %v", kind, name, err)
+	}
 }
 
 func getPvcName(objName string) string {
-	return objName + "-pv-claim"
+	return objName + myproject.PVCNameSuffix
+}
+
+// Queue the object's key for reconciliation.
+func (c *MySqlController) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
 }
 
 func (c *MySqlController) onAdd(obj interface{}) {
-	fmt.Println("Handling MySql add")
+	log.Debug("handling mysql add")
+	c.enqueue(obj)
+}
+
+// Changes to the spec reach the Deployment through drift correction, and
+// growing spec.storage.size resizes the claim.
+func (c *MySqlController) onUpdate(oldObj, newObj interface{}) {
+	log.Debug("handling mysql update")
+	c.enqueue(newObj)
+}
 
-	s := obj.(*mysql.MySql).DeepCopy()
+func (c *MySqlController) onDelete(obj interface{}) {
+	log.Debug("handling mysql delete")
+	c.enqueue(obj)
+}
 
-	_, err := c.makeService(s.Name, 3306)
-	if !errors.IsAlreadyExists(err) && err != nil {
-		return
+// Bring the objects backing a MySql in line with its spec, or carry out its
+// deletion policy if it is being deleted.
+func (c *MySqlController) reconcile(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
 	}
 
-	_, err = c.makePVC(s.Name)
-	if !errors.IsAlreadyExists(err) && err != nil {
-		return
+	log := reconcileLogger(namespace, name)
+	s, err := c.cacheFor(namespace).mySqls.MySqls(namespace).Get(name)
+	if errors.IsNotFound(err) {
+		// The finalizer already ran, nothing is left to clean up.
+		c.phases.set(key, "")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	log = withInstance(log, s)
+	log.Debug("reconciling mysql")
+
+	// Deletion goes ahead even when paused, or the finalizer would keep the
+	// MySql around until someone remembered to resume it.
+	if s.DeletionTimestamp != nil {
+		return c.finalize(log, key, s)
+	}
+
+	// A MySql created paused still needs the finalizer, so that its deletion
+	// policy is carried out should it be deleted before it is resumed.
+	s, err = c.ensureFinalizer(log, s)
+	if err != nil {
+		return err
+	}
+
+	if s.Spec.Paused {
+		return c.pause(log, key, s)
+	}
+
+	if s.Status.Phase == "" {
+		c.phases.set(key, mysql.MySqlPhasePending)
+	}
+
+	err = c.createResources(log, s)
+	if err != nil {
+		c.setPhase(log, key, s, mysql.MySqlPhaseFailed, err.Error())
+		return err
 	}
 
-	podEnvVars := map[string]string{
-		"MYSQL_ROOT_PASSWORD": s.Spec.RootPassword,
+	status := *s.Status.DeepCopy()
+	status.Phase = mysql.MySqlPhaseRunning
+	status.Message = ""
+	status.ExternalAddress = ""
+	if svc, err := c.cacheFor(namespace).services.Services(namespace).Get(name); err == nil {
+		status.ExternalAddress = externalAddress(svc)
 	}
-	podSpec := c.makePodSpec(s.Name, "mysql-ctr", s.Spec.Image, 3306, "mysql-pod-group", podEnvVars)
-	c.makeDeployment(s.Name, *podSpec)
+	return c.setStatus(log, key, s, status)
 }
 
-func (c *MySqlController) onUpdate(oldObj, newObj interface{}) {
-	fmt.Println("Handling MySql update")
+// Record the phase of an instance in its status, writing only when it changes.
+func (c *MySqlController) setPhase(log *zap.Logger, key string, s *mysql.MySql, phase mysql.MySqlPhase, message string) error {
+	status := *s.Status.DeepCopy()
+	status.Phase = phase
+	status.Message = message
+	return c.setStatus(log, key, s, status)
+}
+
+// Replace the status of an instance, writing only when it changes.
+func (c *MySqlController) setStatus(log *zap.Logger, key string, s *mysql.MySql, status mysql.MySqlStatus) error {
+	c.phases.set(key, status.Phase)
+	if !c.config.enabled(featureStatusUpdates) {
+		return nil
+	}
+	if !s.Spec.Paused {
+		status.Conditions = resumeConditions(status.Conditions)
+	}
+	if equality.Semantic.DeepEqual(s.Status, status) {
+		return nil
+	}
 
-	// This is currently a no-op because the MySQL resource currently has nothing
-	// in its spec that can be modified without being disruptive.
+	previous := s.Status.Phase
+	wasPaused := isPaused(s.Status)
+	s = s.DeepCopy()
+	s.Status = status
+	_, err := c.mySqlClientset.MyprojectV1beta1().MySqls(s.Namespace).Update(context.TODO(), s, meta_v1.UpdateOptions{})
+	if err != nil {
+		log.Error("failed to update mysql status", zap.Error(err))
+		return err
+	}
+	switch paused := isPaused(status); {
+	case paused && !wasPaused:
+		log.Info("mysql paused")
+		c.recorder.Event(s, v1.EventTypeNormal, reasonPaused, "Paused, the operator makes no changes until spec.paused is cleared")
+	case !paused && wasPaused:
+		log.Info("mysql resumed")
+		c.recorder.Event(s, v1.EventTypeNormal, reasonResumed, "Resumed, spec.paused was cleared")
+	}
+	if status.Phase == previous {
+		return nil
+	}
+	log.Info("mysql phase changed", zap.String("phase", string(status.Phase)))
+	if status.Phase == mysql.MySqlPhaseRunning {
+		c.recorder.Event(s, v1.EventTypeNormal, reasonRunning, "All resources backing the instance were created")
+	}
+	return nil
 }
 
-// This is a single-instance MySQL operator, so we can get away with deleting
-// all objects related to the app. We have to do it this way also because
-// cascading deletes (to specify all related items) aren't supported.
-func (c *MySqlController) onDelete(obj interface{}) {
-	fmt.Println("Handling MySql delete")
+// Create whatever objects backing a MySql are missing from the cache, and put
+// back the fields the operator owns on those that exist. An object that fails
+// to create with AlreadyExists is either one the cache has not caught up with
+// yet or one created before the operator labelled its objects; it gets the
+// managed labels so the informers see it from now on.
+func (c *MySqlController) createResources(log *zap.Logger, s *mysql.MySql) error {
+	objects := c.cacheFor(s.Namespace)
+
+	service := newService(s, 3306, managedLabels(s, map[string]string{"app": "mysql"}))
+	liveService, err := objects.services.Services(s.Namespace).Get(s.Name)
+	if errors.IsNotFound(err) {
+		_, err = c.makeService(log, service)
+		c.recordCreate(s, "Service", s.Name, err)
+		if errors.IsAlreadyExists(err) {
+			_, err = c.clientset.CoreV1().Services(s.Namespace).Patch(context.TODO(), s.Name, types.MergePatchType, managedLabelsPatch(s), meta_v1.PatchOptions{})
+		}
+	} else if err == nil {
+		err = c.syncService(log, s, liveService, service)
+	}
+	if err != nil {
+		return err
+	}
+
+	storage, err := c.config.Defaults.storage(s)
+	if err != nil {
+		return err
+	}
+	claim := newPVC(s.Namespace, s.Name, storage, s.Spec.Storage.StorageClassName, managedLabels(s, nil))
+	pvc, err := objects.pvcs.PersistentVolumeClaims(s.Namespace).Get(claim.Name)
+	if errors.IsNotFound(err) {
+		pvc, err = c.makePVC(log, claim)
+		c.recordCreate(s, "PersistentVolumeClaim", claim.Name, err)
+		if errors.IsAlreadyExists(err) {
+			pvc, err = c.clientset.CoreV1().PersistentVolumeClaims(s.Namespace).Patch(context.TODO(), claim.Name, types.MergePatchType, managedLabelsPatch(s), meta_v1.PatchOptions{})
+		}
+	} else if err == nil {
+		pvc, err = c.syncPVC(log, s, pvc, claim)
+	}
+	if err == nil {
+		err = c.adoptPVC(log, s, pvc)
+	}
+	if err != nil {
+		return err
+	}
+	if err := c.syncPVCProtection(log, s, pvc, deletionProtected(s)); err != nil {
+		return err
+	}
+	if err := c.resizePVC(log, s, pvc, storage); err != nil {
+		return err
+	}
+	if pvc.Status.Phase == v1.ClaimPending {
+		c.recorder.Eventf(s, v1.EventTypeNormal, reasonPVCPending, "PersistentVolumeClaim %s is waiting to be bound", pvc.Name)
+	}
+
+	tls, err := c.syncTLS(log, s)
+	if err != nil {
+		return err
+	}
+
+	podEnvVars := []v1.EnvVar{rootPasswordEnv(s)}
+	podSpec := c.makePodSpec(s.Name, mysqlContainerName, c.config.Defaults.image(s), 3306, "mysql-pod-group", podEnvVars, tls)
+	podSpec.Spec.Containers[0].Resources = s.Spec.Resources
+	podSpec.Labels = managedLabels(s, podSpec.Labels)
+	if err := securePod(podSpec, s); err != nil {
+		return err
+	}
+	deployment := newDeployment(s.Namespace, s.Name, *podSpec, managedLabels(s, nil))
+	schedulePod(&deployment.Spec.Template.Spec, s, replicas(deployment.Spec.Replicas))
+	if err := customizePod(&deployment.Spec.Template, s); err != nil {
+		c.recorder.Event(s, v1.EventTypeWarning, reasonFailedCreate, err.Error())
+		return err
+	}
+	liveDeployment, err := objects.deployments.Deployments(s.Namespace).Get(s.Name)
+	if errors.IsNotFound(err) {
+		_, err = c.makeDeployment(log, deployment)
+		c.recordCreate(s, "Deployment", s.Name, err)
+		if errors.IsAlreadyExists(err) {
+			_, err = c.clientset.AppsV1().Deployments(s.Namespace).Patch(context.TODO(), s.Name, types.MergePatchType, managedLabelsPatch(s), meta_v1.PatchOptions{})
+		}
+	} else if err == nil {
+		c.recordCertRotation(log, s, liveDeployment, tls)
+		err = c.syncDeployment(log, s, liveDeployment, deployment)
+	}
+	if err != nil {
+		return err
+	}
+
+	pdb := newPDB(s, replicas(deployment.Spec.Replicas), managedLabels(s, nil))
+	livePDB, err := objects.pdbs.PodDisruptionBudgets(s.Namespace).Get(s.Name)
+	if errors.IsNotFound(err) {
+		_, err = c.makePDB(log, pdb)
+		c.recordCreate(s, "PodDisruptionBudget", s.Name, err)
+		if errors.IsAlreadyExists(err) {
+			_, err = c.clientset.PolicyV1().PodDisruptionBudgets(s.Namespace).Patch(context.TODO(), s.Name, types.MergePatchType, managedLabelsPatch(s), meta_v1.PatchOptions{})
+		}
+	} else if err == nil {
+		err = c.syncPDB(log, s, livePDB, pdb)
+	}
+	if err != nil {
+		return err
+	}
+
+	policy := newNetworkPolicy(s, c.config.NetworkPolicy.operatorPeer(), &deployment.Spec.Template.Spec, managedLabels(s, nil))
+	livePolicy, err := objects.policies.NetworkPolicies(s.Namespace).Get(s.Name)
+	if len(s.Spec.NetworkPolicy.AllowFrom) == 0 {
+		if err == nil {
+			err = c.removeNetworkPolicy(log, s, livePolicy.Name)
+		} else if errors.IsNotFound(err) {
+			err = nil
+		}
+	} else if errors.IsNotFound(err) {
+		_, err = c.makeNetworkPolicy(log, policy)
+		c.recordCreate(s, "NetworkPolicy", s.Name, err)
+		if errors.IsAlreadyExists(err) {
+			_, err = c.clientset.NetworkingV1().NetworkPolicies(s.Namespace).Patch(context.TODO(), s.Name, types.MergePatchType, managedLabelsPatch(s), meta_v1.PatchOptions{})
+		}
+	} else if err == nil {
+		err = c.syncNetworkPolicy(log, s, livePolicy, policy)
+	}
+	return err
+}
 
-	s := obj.(*mysql.MySql).DeepCopy()
+// Delete the objects backing a MySql, leaving its claim in place unless
+// deletePVC is set. Objects without a fixed name are found by the managed
+// labels, so other instances in the namespace are left alone. We have to do
+// it this way because cascading deletes (to specify all related items) aren't
+// supported.
+func (c *MySqlController) cleanup(log *zap.Logger, s *mysql.MySql, deletePVC bool) error {
+	log.Info("cleaning up mysql")
+	namespace, name := s.Namespace, s.Name
+
+	var failed []string
+	deleteFailed := func(kind string, err error) {
+		if errors.IsNotFound(err) {
+			return
+		}
+		failed = append(failed, kind)
+		log.Error("failed to delete "+kind, zap.Error(err))
+		c.recorder.Eventf(s, v1.EventTypeWarning, reasonFailedDelete, "Failed to delete %s, deletion is blocked until it succeeds: %v", kind, err)
+	}
 	var delOpts meta_v1.DeleteOptions
-	listOpts := meta_v1.ListOptions{LabelSelector: "app=mysql"}
+	listOpts := meta_v1.ListOptions{LabelSelector: labels.SelectorFromSet(managedLabels(s, nil)).String()}
 
-	// Delete deployments.
-	appsClient := c.context.Clientset.AppsV1beta2()
-	err := appsClient.Deployments(v1.NamespaceDefault).DeleteCollection(&delOpts, listOpts)
+	// Delete the deployment.
+	appsClient := c.clientset.AppsV1()
+	err := appsClient.Deployments(namespace).Delete(context.TODO(), name, delOpts)
 	if err != nil {
-		fmt.Println("failed to delete deployment:", err)
+		deleteFailed("deployment", err)
 	}
 
 	// Delete service.
-	coreV1Client := c.context.Clientset.CoreV1()
-	err = coreV1Client.Services(v1.NamespaceDefault).Delete(s.Name, &delOpts)
+	coreV1Client := c.clientset.CoreV1()
+	err = coreV1Client.Services(namespace).Delete(context.TODO(), name, delOpts)
+	if err != nil {
+		deleteFailed("service", err)
+	}
+
+	// Delete the pod disruption budget.
+	err = c.clientset.PolicyV1().PodDisruptionBudgets(namespace).Delete(context.TODO(), name, delOpts)
 	if err != nil {
-		fmt.Println("failed to delete service:", err)
+		deleteFailed("pod disruption budget", err)
+	}
+
+	// Delete the network policy.
+	err = c.clientset.NetworkingV1().NetworkPolicies(namespace).Delete(context.TODO(), name, delOpts)
+	if err != nil {
+		deleteFailed("network policy", err)
 	}
 
 	// Delete replica sets.
-	err = appsClient.ReplicaSets(v1.NamespaceDefault).DeleteCollection(&delOpts, listOpts)
+	err = appsClient.ReplicaSets(namespace).DeleteCollection(context.TODO(), delOpts, listOpts)
 	if err != nil {
-		fmt.Println("failed to delete replication controller:", err)
+		deleteFailed("replica sets", err)
 	}
 
 	// Delete PVC.
-	err = coreV1Client.PersistentVolumeClaims(v1.NamespaceDefault).Delete(getPvcName(s.Name), &delOpts)
-	if err != nil {
-		fmt.Println("failed to delete pvc:", err)
+	if deletePVC {
+		err = coreV1Client.PersistentVolumeClaims(namespace).Delete(context.TODO(), getPvcName(name), delOpts)
+		if err != nil {
+			deleteFailed("pvc", err)
+		}
 	}
 
 	// Delete pods.
-	err = coreV1Client.Pods(v1.NamespaceDefault).DeleteCollection(&delOpts, listOpts)
+	err = coreV1Client.Pods(namespace).DeleteCollection(context.TODO(), delOpts, listOpts)
 	if err != nil {
-		fmt.Println("failed to delete pod:", err)
+		deleteFailed("pods", err)
 	}
 
+	// Delete the certificate Secrets the operator generated.
+	err = coreV1Client.Secrets(namespace).DeleteCollection(context.TODO(), delOpts, listOpts)
+	if err != nil {
+		deleteFailed("secrets", err)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to delete %s", strings.Join(failed, ", "))
+	}
+	c.recorder.Event(s, v1.EventTypeNormal, reasonDeleted, "Deleted all resources backing the instance")
+	return nil
 }
@@ -0,0 +1,1023 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tonya11en/mysql-operator/pkg/apis/myproject"
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	mysqlfake "github.com/tonya11en/mysql-operator/pkg/client/clientset/versioned/fake"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	psaapi "k8s.io/pod-security-admission/api"
+	psapolicy "k8s.io/pod-security-admission/policy"
+)
+
+const testKey = "default/db"
+
+// fixture is a controller running against fake clientsets. Its caches are
+// only filled by syncCaches, so a test decides what the controller has seen.
+type fixture struct {
+	t          *testing.T
+	kube       *kubefake.Clientset
+	mySqls     *mysqlfake.Clientset
+	dynamic    *dynamicfake.FakeDynamicClient
+	recorder   *record.FakeRecorder
+	controller *MySqlController
+	cache      *namespaceCache
+}
+
+// Kinds of the resources the fake clientset deletes collections of.
+var collectionKinds = map[string]string{
+	"deployments": "Deployment",
+	"replicasets": "ReplicaSet",
+	"pods":        "Pod",
+	"secrets":     "Secret",
+}
+
+func newFixture(t *testing.T, objects ...runtime.Object) *fixture {
+	var kubeObjects, mySqlObjects []runtime.Object
+	for _, obj := range objects {
+		if _, ok := obj.(*mysql.MySql); ok {
+			mySqlObjects = append(mySqlObjects, obj)
+		} else {
+			kubeObjects = append(kubeObjects, obj)
+		}
+	}
+
+	f := &fixture{
+		t:        t,
+		kube:     kubefake.NewSimpleClientset(kubeObjects...),
+		mySqls:   mysqlfake.NewSimpleClientset(mySqlObjects...),
+		dynamic:  dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+		recorder: record.NewFakeRecorder(100),
+	}
+	// The object tracker has no support for deleting collections.
+	f.kube.PrependReactor("delete-collection", "*", f.deleteCollection)
+
+	config := defaultConfig()
+	config.Features[featureStatusUpdates] = true
+	f.controller = newMySqlController(f.kube, f.mySqls, f.dynamic, f.recorder, newHealthChecker(), config)
+	f.cache = f.controller.addNamespace(v1.NamespaceAll)
+	f.syncCaches()
+	return f
+}
+
+func (f *fixture) deleteCollection(action k8stesting.Action) (bool, runtime.Object, error) {
+	gvr := action.GetResource()
+	kind, ok := collectionKinds[gvr.Resource]
+	if !ok {
+		return false, nil, nil
+	}
+	list, err := f.kube.Tracker().List(gvr, gvr.GroupVersion().WithKind(kind), action.GetNamespace())
+	if err != nil {
+		return true, nil, err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return true, nil, err
+	}
+	selector := action.(k8stesting.DeleteCollectionAction).GetListRestrictions().Labels
+	for _, item := range items {
+		object, err := meta.Accessor(item)
+		if err != nil {
+			return true, nil, err
+		}
+		if !selector.Matches(labels.Set(object.GetLabels())) {
+			continue
+		}
+		if err := f.kube.Tracker().Delete(gvr, object.GetNamespace(), object.GetName()); err != nil {
+			return true, nil, err
+		}
+	}
+	return true, nil, nil
+}
+
+// Fill the caches with what the fake clientsets hold, as the informers would
+// once they caught up.
+func (f *fixture) syncCaches() {
+	opts := meta_v1.ListOptions{LabelSelector: managedSelector}
+	lists := []struct {
+		informer cache.SharedIndexInformer
+		list     func() (runtime.Object, error)
+	}{
+		{f.cache.mySqlInformer, func() (runtime.Object, error) {
+			return f.mySqls.MyprojectV1beta1().MySqls(v1.NamespaceAll).List(context.TODO(), meta_v1.ListOptions{})
+		}},
+		{f.cache.kubeInformers.Core().V1().Services().Informer(), func() (runtime.Object, error) {
+			return f.kube.CoreV1().Services(v1.NamespaceAll).List(context.TODO(), opts)
+		}},
+		{f.cache.kubeInformers.Core().V1().PersistentVolumeClaims().Informer(), func() (runtime.Object, error) {
+			return f.kube.CoreV1().PersistentVolumeClaims(v1.NamespaceAll).List(context.TODO(), opts)
+		}},
+		{f.cache.kubeInformers.Apps().V1().Deployments().Informer(), func() (runtime.Object, error) {
+			return f.kube.AppsV1().Deployments(v1.NamespaceAll).List(context.TODO(), opts)
+		}},
+		{f.cache.kubeInformers.Policy().V1().PodDisruptionBudgets().Informer(), func() (runtime.Object, error) {
+			return f.kube.PolicyV1().PodDisruptionBudgets(v1.NamespaceAll).List(context.TODO(), opts)
+		}},
+		{f.cache.kubeInformers.Networking().V1().NetworkPolicies().Informer(), func() (runtime.Object, error) {
+			return f.kube.NetworkingV1().NetworkPolicies(v1.NamespaceAll).List(context.TODO(), opts)
+		}},
+	}
+	for _, l := range lists {
+		list, err := l.list()
+		if err != nil {
+			f.t.Fatalf("failed to list objects for the cache. %+v", err)
+		}
+		objects, err := meta.ExtractList(list)
+		if err != nil {
+			f.t.Fatal(err)
+		}
+		items := make([]interface{}, len(objects))
+		for i := range objects {
+			items[i] = objects[i]
+		}
+		if err := l.informer.GetIndexer().Replace(items, ""); err != nil {
+			f.t.Fatal(err)
+		}
+	}
+}
+
+// The events recorded since the last call.
+func (f *fixture) events() []string {
+	var events []string
+	for {
+		select {
+		case event := <-f.recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+// The writes made through a fake clientset since the last call, as "verb
+// resource".
+func writes(fake *k8stesting.Fake) []string {
+	var verbs []string
+	for _, action := range fake.Actions() {
+		switch action.GetVerb() {
+		case "get", "list", "watch":
+			continue
+		}
+		verbs = append(verbs, action.GetVerb()+" "+action.GetResource().Resource)
+	}
+	fake.ClearActions()
+	return verbs
+}
+
+func (f *fixture) kubeWrites() []string {
+	return writes(&f.kube.Fake)
+}
+
+func (f *fixture) mySqlWrites() []string {
+	return writes(&f.mySqls.Fake)
+}
+
+// Reconcile key, failing the test on an error.
+func (f *fixture) reconcile(key string) {
+	if err := f.controller.reconcile(key); err != nil {
+		f.t.Fatalf("reconcile failed. %+v", err)
+	}
+}
+
+func (f *fixture) getMySql(name string) *mysql.MySql {
+	s, err := f.mySqls.MyprojectV1beta1().MySqls("default").Get(context.TODO(), name, meta_v1.GetOptions{})
+	if err != nil {
+		f.t.Fatalf("failed to get mysql. %+v", err)
+	}
+	return s
+}
+
+// Mark the stored MySql as deleted, as the API server does for an object
+// with finalizers.
+func (f *fixture) markDeleted(name string) {
+	s := f.getMySql(name)
+	now := meta_v1.Now()
+	s.DeletionTimestamp = &now
+	if _, err := f.mySqls.MyprojectV1beta1().MySqls("default").Update(context.TODO(), s, meta_v1.UpdateOptions{}); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+func checkList(t *testing.T, what string, got []string, want []string) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %s %q, want %q", what, got, want)
+	}
+}
+
+func withFinalizer(s *mysql.MySql) {
+	s.Finalizers = append(s.Finalizers, mySqlFinalizer)
+}
+
+func withDeletionTimestamp(s *mysql.MySql) {
+	now := meta_v1.Now()
+	s.DeletionTimestamp = &now
+}
+
+func testPod(name string, owner string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: meta_v1.ObjectMeta{
+		Name:      name,
+		Namespace: "default",
+		Labels:    managedLabels(testMySql(owner), podSelector(owner)),
+	}}
+}
+
+func TestReconcileCreates(t *testing.T) {
+	f := newFixture(t, testMySql("db"))
+	f.reconcile(testKey)
+
+	checkList(t, "kube writes", f.kubeWrites(), []string{
+		"create services",
+		"create persistentvolumeclaims",
+		"create deployments",
+		"create poddisruptionbudgets",
+	})
+	// The finalizer is added before anything is created, the status is
+	// written last.
+	checkList(t, "mysql writes", f.mySqlWrites(), []string{"update mysqls", "update mysqls"})
+	checkList(t, "events", f.events(), []string{
+		"Normal Created Created Service db",
+		"Normal Created Created PersistentVolumeClaim db-pv-claim",
+		"Normal Created Created Deployment db",
+		"Normal Created Created PodDisruptionBudget db",
+		"Normal Running All resources backing the instance were created",
+	})
+
+	s := f.getMySql("db")
+	if !hasFinalizer(s) {
+		t.Errorf("got finalizers %q, want %q", s.Finalizers, mySqlFinalizer)
+	}
+	if s.Status.Phase != mysql.MySqlPhaseRunning {
+		t.Errorf("got phase %q, want %q", s.Status.Phase, mysql.MySqlPhaseRunning)
+	}
+	deployment, err := f.kube.AppsV1().Deployments("default").Get(context.TODO(), "db", meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deployment.Labels[myproject.InstanceLabel] != "db" || deployment.Labels[myproject.ManagedByLabel] != myproject.ManagedByValue {
+		t.Errorf("got deployment labels %v, want the managed labels", deployment.Labels)
+	}
+}
+
+// The data volume is where mysqld keeps its data whatever the MySql is
+// called, with no other volume mounted over it.
+func TestReconcileMountsData(t *testing.T) {
+	f := newFixture(t, testMySql("db"))
+	f.reconcile(testKey)
+
+	deployment, err := f.kube.AppsV1().Deployments("default").Get(context.TODO(), "db", meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var volumes []string
+	for _, mount := range deployment.Spec.Template.Spec.Containers[0].VolumeMounts {
+		if mount.MountPath == "/var/lib/mysql" {
+			volumes = append(volumes, mount.Name)
+		}
+	}
+	checkList(t, "volumes mounted at /var/lib/mysql", volumes, []string{dataVolumeName})
+}
+
+// The pod of a MySql meets the restricted Pod Security Standard, whichever
+// of the allowed seccomp profiles it asks for.
+func TestReconcilePodSecurity(t *testing.T) {
+	evaluator, err := psapolicy.NewEvaluator(psapolicy.DefaultChecks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	restricted := psaapi.LevelVersion{Level: psaapi.LevelRestricted, Version: psaapi.LatestVersion()}
+	tests := []struct {
+		profile string
+		want    v1.SeccompProfileType
+	}{
+		{"", v1.SeccompProfileTypeRuntimeDefault},
+		{"runtime/default", v1.SeccompProfileTypeRuntimeDefault},
+		{"docker/default", v1.SeccompProfileTypeRuntimeDefault},
+		{"localhost/mysql.json", v1.SeccompProfileTypeLocalhost},
+	}
+	for _, test := range tests {
+		t.Run(test.profile, func(t *testing.T) {
+			f := newFixture(t, testMySql("db", func(s *mysql.MySql) { s.Spec.Security.SeccompProfile = test.profile }))
+			f.reconcile(testKey)
+
+			deployment, err := f.kube.AppsV1().Deployments("default").Get(context.TODO(), "db", meta_v1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			pod := deployment.Spec.Template
+			for _, result := range evaluator.EvaluatePod(restricted, &pod.ObjectMeta, &pod.Spec) {
+				if !result.Allowed {
+					t.Errorf("restricted: %s: %s", result.ForbiddenReason, result.ForbiddenDetail)
+				}
+			}
+			if got := pod.Spec.SecurityContext.SeccompProfile; got == nil || got.Type != test.want {
+				t.Errorf("got pod seccomp profile %v, want %s", got, test.want)
+			}
+			if got := pod.Spec.Containers[0].SecurityContext.SeccompProfile; got == nil || got.Type != test.want {
+				t.Errorf("got container seccomp profile %v, want %s", got, test.want)
+			}
+			if _, ok := pod.Annotations[v1.SeccompPodAnnotationKey]; ok {
+				t.Errorf("got the deprecated seccomp annotation, want the security context fields")
+			}
+		})
+	}
+}
+
+func TestReconcileIsIdempotent(t *testing.T) {
+	f := newFixture(t, testMySql("db"))
+	f.reconcile(testKey)
+	f.syncCaches()
+	f.kubeWrites()
+	f.mySqlWrites()
+	f.events()
+
+	f.reconcile(testKey)
+
+	checkList(t, "kube writes", f.kubeWrites(), nil)
+	checkList(t, "mysql writes", f.mySqlWrites(), nil)
+	checkList(t, "events", f.events(), nil)
+}
+
+func TestReconcileRecreates(t *testing.T) {
+	f := newFixture(t, testMySql("db"))
+	f.reconcile(testKey)
+	if err := f.kube.CoreV1().Services("default").Delete(context.TODO(), "db", meta_v1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	f.syncCaches()
+	f.kubeWrites()
+	f.mySqlWrites()
+	f.events()
+
+	f.reconcile(testKey)
+
+	checkList(t, "kube writes", f.kubeWrites(), []string{"create services"})
+	checkList(t, "mysql writes", f.mySqlWrites(), nil)
+	checkList(t, "events", f.events(), []string{"Normal Created Created Service db"})
+}
+
+func TestReconcileCorrectsDrift(t *testing.T) {
+	f := newFixture(t, testMySql("db"))
+	f.reconcile(testKey)
+	deployment, err := f.kube.AppsV1().Deployments("default").Get(context.TODO(), "db", meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var zero int32
+	deployment.Spec.Replicas = &zero
+	if _, err := f.kube.AppsV1().Deployments("default").Update(context.TODO(), deployment, meta_v1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	f.syncCaches()
+	f.kubeWrites()
+	f.events()
+
+	f.reconcile(testKey)
+
+	checkList(t, "kube writes", f.kubeWrites(), []string{"update deployments"})
+	checkList(t, "events", f.events(), []string{"Normal DriftCorrected Corrected changes made to Deployment db: spec.replicas"})
+}
+
+// The seccomp annotation earlier releases put on the pod template is removed.
+func TestReconcileRemovesSeccompAnnotation(t *testing.T) {
+	f := newFixture(t, testMySql("db"))
+	f.reconcile(testKey)
+	deployment, err := f.kube.AppsV1().Deployments("default").Get(context.TODO(), "db", meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployment.Spec.Template.Annotations[v1.SeccompPodAnnotationKey] = "localhost/old.json"
+	if _, err := f.kube.AppsV1().Deployments("default").Update(context.TODO(), deployment, meta_v1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	f.syncCaches()
+	f.kubeWrites()
+	f.events()
+
+	f.reconcile(testKey)
+
+	checkList(t, "kube writes", f.kubeWrites(), []string{"update deployments"})
+	checkList(t, "events", f.events(), []string{"Normal DriftCorrected Corrected changes made to Deployment db: spec.template.metadata.annotations"})
+}
+
+// Two MySqls in a namespace each select their own pods only.
+func TestReconcileSelectorsDoNotOverlap(t *testing.T) {
+	f := newFixture(t, testMySql("db"), testMySql("other"))
+	f.reconcile(testKey)
+	f.reconcile("default/other")
+
+	for _, name := range []string{"db", "other"} {
+		service, err := f.kube.CoreV1().Services("default").Get(context.TODO(), name, meta_v1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		deployment, err := f.kube.AppsV1().Deployments("default").Get(context.TODO(), name, meta_v1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		deploymentSelector, err := meta_v1.LabelSelectorAsSelector(deployment.Spec.Selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		selectors := map[string]labels.Selector{
+			"service":    labels.SelectorFromSet(service.Spec.Selector),
+			"deployment": deploymentSelector,
+		}
+		for _, owner := range []string{"db", "other"} {
+			pod := testPod(owner+"-0", owner)
+			for what, selector := range selectors {
+				if got, want := selector.Matches(labels.Set(pod.Labels)), owner == name; got != want {
+					t.Errorf("%s %s matches pod of %s: got %v, want %v", what, name, owner, got, want)
+				}
+			}
+		}
+		if !deploymentSelector.Matches(labels.Set(deployment.Spec.Template.Labels)) {
+			t.Errorf("deployment %s does not select its own pod template labels %v", name, deployment.Spec.Template.Labels)
+		}
+	}
+}
+
+// A Deployment made with the selector shared by every MySql is recreated, its
+// selector cannot be updated.
+func TestReconcileRecreatesDeploymentSelector(t *testing.T) {
+	f := newFixture(t, testMySql("db"))
+	f.reconcile(testKey)
+	deployment, err := f.kube.AppsV1().Deployments("default").Get(context.TODO(), "db", meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployment.Spec.Selector.MatchLabels = map[string]string{"app": "mysql"}
+	if _, err := f.kube.AppsV1().Deployments("default").Update(context.TODO(), deployment, meta_v1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	f.syncCaches()
+	f.kubeWrites()
+	f.events()
+
+	f.reconcile(testKey)
+
+	checkList(t, "kube writes", f.kubeWrites(), []string{"delete deployments"})
+	checkList(t, "events", f.events(), []string{"Normal Recreating Recreating Deployment db to change its selector"})
+
+	f.syncCaches()
+	f.reconcile(testKey)
+
+	checkList(t, "kube writes", f.kubeWrites(), []string{"create deployments"})
+}
+
+// Objects made before the operator labelled what it creates are missing from
+// the caches, creating them fails and they are labelled instead.
+func TestReconcileAlreadyExists(t *testing.T) {
+	f := newFixture(t,
+		testMySql("db"),
+		&v1.Service{ObjectMeta: meta_v1.ObjectMeta{Name: "db", Namespace: "default", Labels: map[string]string{"app": "mysql"}}},
+		&appsv1.Deployment{ObjectMeta: meta_v1.ObjectMeta{Name: "db", Namespace: "default", Labels: map[string]string{"app": "mysql"}}},
+	)
+	f.reconcile(testKey)
+
+	checkList(t, "kube writes", f.kubeWrites(), []string{
+		"create services",
+		"patch services",
+		"create persistentvolumeclaims",
+		"create deployments",
+		"patch deployments",
+		"create poddisruptionbudgets",
+	})
+	checkList(t, "events", f.events(), []string{
+		"Normal Created Created PersistentVolumeClaim db-pv-claim",
+		"Normal Created Created PodDisruptionBudget db",
+		"Normal Running All resources backing the instance were created",
+	})
+
+	service, err := f.kube.CoreV1().Services("default").Get(context.TODO(), "db", meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"app": "mysql", myproject.ManagedByLabel: myproject.ManagedByValue, myproject.InstanceLabel: "db"}
+	if !reflect.DeepEqual(service.Labels, want) {
+		t.Errorf("got service labels %v, want %v", service.Labels, want)
+	}
+}
+
+func TestReconcileDeletes(t *testing.T) {
+	f := newFixture(t, testMySql("db"), testPod("db-0", "db"), testPod("other-0", "other"))
+	f.reconcile(testKey)
+	f.markDeleted("db")
+	f.syncCaches()
+	f.kubeWrites()
+	f.mySqlWrites()
+	f.events()
+
+	f.reconcile(testKey)
+
+	checkList(t, "kube writes", f.kubeWrites(), []string{
+		"delete deployments",
+		"delete services",
+		"delete poddisruptionbudgets",
+		"delete networkpolicies",
+		"delete-collection replicasets",
+		"delete persistentvolumeclaims",
+		"delete-collection pods",
+		"delete-collection secrets",
+	})
+	// The phase is set to Deleting, then the finalizer removed.
+	checkList(t, "mysql writes", f.mySqlWrites(), []string{"update mysqls", "update mysqls"})
+	checkList(t, "events", f.events(), []string{"Normal Deleted Deleted all resources backing the instance"})
+
+	if s := f.getMySql("db"); hasFinalizer(s) {
+		t.Errorf("got finalizers %q, want none", s.Finalizers)
+	}
+	if _, err := f.kube.AppsV1().Deployments("default").Get(context.TODO(), "db", meta_v1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("got deployment error %v, want NotFound", err)
+	}
+	if _, err := f.kube.CoreV1().PersistentVolumeClaims("default").Get(context.TODO(), getPvcName("db"), meta_v1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("got pvc error %v, want NotFound", err)
+	}
+	pods, err := f.kube.CoreV1().Pods("default").List(context.TODO(), meta_v1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, pod := range pods.Items {
+		names = append(names, pod.Name)
+	}
+	checkList(t, "pods", names, []string{"other-0"})
+}
+
+func withDeletionPolicy(policy mysql.DeletionPolicy) func(*mysql.MySql) {
+	return func(s *mysql.MySql) { s.Spec.DeletionPolicy = policy }
+}
+
+// Reconcile the MySql of testKey, then delete it. Returns the error of the
+// reconcile that runs the finalizer.
+func (f *fixture) reconcileDeleted() error {
+	f.reconcile(testKey)
+	f.markDeleted("db")
+	f.syncCaches()
+	f.kubeWrites()
+	f.mySqlWrites()
+	f.events()
+	return f.controller.reconcile(testKey)
+}
+
+func (f *fixture) snapshots() dynamic.ResourceInterface {
+	return f.dynamic.Resource(volumeSnapshotResource).Namespace("default")
+}
+
+func TestFinalizeDelete(t *testing.T) {
+	f := newFixture(t, testMySql("db", withDeletionPolicy(mysql.DeletionPolicyDelete)))
+	if err := f.reconcileDeleted(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.kube.CoreV1().PersistentVolumeClaims("default").Get(context.TODO(), getPvcName("db"), meta_v1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("got pvc error %v, want NotFound", err)
+	}
+	if actions := f.dynamic.Actions(); len(actions) != 0 {
+		t.Errorf("got snapshot actions %v, want none", actions)
+	}
+	if s := f.getMySql("db"); hasFinalizer(s) {
+		t.Errorf("got finalizers %q, want none", s.Finalizers)
+	}
+}
+
+func TestFinalizeRetain(t *testing.T) {
+	f := newFixture(t, testMySql("db", withDeletionPolicy(mysql.DeletionPolicyRetain)))
+	if err := f.reconcileDeleted(); err != nil {
+		t.Fatal(err)
+	}
+
+	pvc, err := f.kube.CoreV1().PersistentVolumeClaims("default").Get(context.TODO(), getPvcName("db"), meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("retained pvc is gone. %+v", err)
+	}
+	if pvc.Labels[retainedLabel] != "true" || pvc.Labels[retainedInstanceLabel] != "db" {
+		t.Errorf("got pvc labels %v, want it marked as retained by db", pvc.Labels)
+	}
+	if _, err := f.kube.AppsV1().Deployments("default").Get(context.TODO(), "db", meta_v1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("got deployment error %v, want NotFound", err)
+	}
+	checkList(t, "events", f.events(), []string{
+		"Normal Retained Retained PersistentVolumeClaim db-pv-claim",
+		"Normal Deleted Deleted all resources backing the instance",
+	})
+	if s := f.getMySql("db"); hasFinalizer(s) {
+		t.Errorf("got finalizers %q, want none", s.Finalizers)
+	}
+}
+
+func TestFinalizeSnapshot(t *testing.T) {
+	f := newFixture(t, testMySql("db", withDeletionPolicy(mysql.DeletionPolicySnapshot)))
+	f.controller.config.Defaults.VolumeSnapshotClass = "csi-snapclass"
+	if err := f.reconcileDeleted(); err != errSnapshotPending {
+		t.Fatalf("got error %v, want %v", err, errSnapshotPending)
+	}
+
+	name := getSnapshotName(f.getMySql("db"))
+	snapshot, err := f.snapshots().Get(context.TODO(), name, meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("snapshot was not created. %+v", err)
+	}
+	if got, want := snapshot.GetAPIVersion(), "snapshot.storage.k8s.io/v1"; got != want {
+		t.Errorf("got apiVersion %q, want %q", got, want)
+	}
+	if claim, _, _ := unstructured.NestedString(snapshot.Object, "spec", "source", "persistentVolumeClaimName"); claim != getPvcName("db") {
+		t.Errorf("got spec.source.persistentVolumeClaimName %q, want %q", claim, getPvcName("db"))
+	}
+	if class, _, _ := unstructured.NestedString(snapshot.Object, "spec", "volumeSnapshotClassName"); class != "csi-snapclass" {
+		t.Errorf("got spec.volumeSnapshotClassName %q, want %q", class, "csi-snapclass")
+	}
+	// The volume is kept until the snapshot is ready.
+	if _, err := f.kube.CoreV1().PersistentVolumeClaims("default").Get(context.TODO(), getPvcName("db"), meta_v1.GetOptions{}); err != nil {
+		t.Errorf("pvc was deleted before the snapshot was ready. %+v", err)
+	}
+	if s := f.getMySql("db"); !hasFinalizer(s) {
+		t.Errorf("finalizer was removed before the snapshot was ready")
+	}
+
+	if err := unstructured.SetNestedField(snapshot.Object, true, "status", "readyToUse"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.snapshots().Update(context.TODO(), snapshot, meta_v1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	f.syncCaches()
+	f.reconcile(testKey)
+
+	if _, err := f.kube.CoreV1().PersistentVolumeClaims("default").Get(context.TODO(), getPvcName("db"), meta_v1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("got pvc error %v, want NotFound", err)
+	}
+	if s := f.getMySql("db"); hasFinalizer(s) {
+		t.Errorf("got finalizers %q, want none", s.Finalizers)
+	}
+}
+
+func withPaused(s *mysql.MySql) {
+	s.Spec.Paused = true
+}
+
+func TestReconcilePaused(t *testing.T) {
+	f := newFixture(t, testMySql("db", withFinalizer, withPaused))
+	f.reconcile(testKey)
+
+	checkList(t, "kube writes", f.kubeWrites(), nil)
+	checkList(t, "mysql writes", f.mySqlWrites(), []string{"update mysqls"})
+	checkList(t, "events", f.events(), []string{"Normal Paused Paused, the operator makes no changes until spec.paused is cleared"})
+	s := f.getMySql("db")
+	if s.Status.Phase != mysql.MySqlPhasePaused {
+		t.Errorf("got phase %q, want %q", s.Status.Phase, mysql.MySqlPhasePaused)
+	}
+	if !isPaused(s.Status) {
+		t.Errorf("got conditions %v, want Paused", s.Status.Conditions)
+	}
+
+	// Clearing spec.paused creates what is missing and flips the condition.
+	s.Spec.Paused = false
+	if _, err := f.mySqls.MyprojectV1beta1().MySqls("default").Update(context.TODO(), s, meta_v1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	f.syncCaches()
+	f.mySqlWrites()
+	f.reconcile(testKey)
+
+	checkList(t, "kube writes", f.kubeWrites(), []string{
+		"create services",
+		"create persistentvolumeclaims",
+		"create deployments",
+		"create poddisruptionbudgets",
+	})
+	checkList(t, "events", f.events(), []string{
+		"Normal Created Created Service db",
+		"Normal Created Created PersistentVolumeClaim db-pv-claim",
+		"Normal Created Created Deployment db",
+		"Normal Created Created PodDisruptionBudget db",
+		"Normal Resumed Resumed, spec.paused was cleared",
+		"Normal Running All resources backing the instance were created",
+	})
+	if s := f.getMySql("db"); isPaused(s.Status) {
+		t.Errorf("got conditions %v, want not Paused", s.Status.Conditions)
+	}
+}
+
+// A MySql created paused gets the finalizer before anything else, so deleting
+// it before it is resumed still carries out its deletion policy.
+func TestReconcilePausedAddsFinalizer(t *testing.T) {
+	f := newFixture(t, testMySql("db", withPaused))
+	f.reconcile(testKey)
+
+	checkList(t, "kube writes", f.kubeWrites(), nil)
+	// The finalizer is added, then the Paused status written.
+	checkList(t, "mysql writes", f.mySqlWrites(), []string{"update mysqls", "update mysqls"})
+	if s := f.getMySql("db"); !hasFinalizer(s) {
+		t.Errorf("got finalizers %q, want %q", s.Finalizers, mySqlFinalizer)
+	}
+}
+
+// Pausing does not hold up a deletion, the finalizer still runs.
+func TestReconcilePausedDeletes(t *testing.T) {
+	f := newFixture(t, testMySql("db"))
+	f.reconcile(testKey)
+	s := f.getMySql("db")
+	s.Spec.Paused = true
+	if _, err := f.mySqls.MyprojectV1beta1().MySqls("default").Update(context.TODO(), s, meta_v1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	f.markDeleted("db")
+	f.syncCaches()
+	f.kubeWrites()
+
+	f.reconcile(testKey)
+
+	if s := f.getMySql("db"); hasFinalizer(s) {
+		t.Errorf("got finalizers %q, want none", s.Finalizers)
+	}
+	if _, err := f.kube.AppsV1().Deployments("default").Get(context.TODO(), "db", meta_v1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("got deployment error %v, want NotFound", err)
+	}
+}
+
+// Every rule of the NetworkPolicy, the sidecar ports' included, only lets in
+// the allowed sources and the operator's pods in the operator's namespace.
+func TestReconcileNetworkPolicy(t *testing.T) {
+	shop := mysql.NetworkPolicyPeer{PodSelector: &meta_v1.LabelSelector{MatchLabels: map[string]string{"app": "shop"}}}
+	f := newFixture(t, testMySql("db", withNetworkPolicy(shop), withPodTemplate(mysql.PodTemplateSpec{
+		Containers: []v1.Container{{Name: "exporter", Image: "prom/mysqld-exporter", Ports: []v1.ContainerPort{{ContainerPort: 9104}}}},
+	})))
+	f.controller.config.NetworkPolicy.OperatorNamespace = "ops"
+	f.reconcile(testKey)
+
+	policy, err := f.kube.NetworkingV1().NetworkPolicies("default").Get(context.TODO(), "db", meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []networkingv1.NetworkPolicyPeer{
+		{
+			PodSelector:       &meta_v1.LabelSelector{MatchLabels: map[string]string{"app": "mysql-operator"}},
+			NamespaceSelector: &meta_v1.LabelSelector{MatchLabels: map[string]string{namespaceNameLabel: "ops"}},
+		},
+		{PodSelector: shop.PodSelector},
+	}
+	if len(policy.Spec.Ingress) != 2 {
+		t.Fatalf("got %d ingress rules, want 2", len(policy.Spec.Ingress))
+	}
+	for i, rule := range policy.Spec.Ingress {
+		if !equality.Semantic.DeepEqual(rule.From, want) {
+			t.Errorf("got ingress[%d].from %v, want %v", i, rule.From, want)
+		}
+	}
+	if port := policy.Spec.Ingress[1].Ports[0].Port.IntValue(); port != 9104 {
+		t.Errorf("got sidecar port %d, want 9104", port)
+	}
+}
+
+// With sidecarAllowFrom set the sidecar ports let in those sources instead,
+// and MySQL still only the allowed ones.
+func TestReconcileSidecarNetworkPolicy(t *testing.T) {
+	shop := mysql.NetworkPolicyPeer{PodSelector: &meta_v1.LabelSelector{MatchLabels: map[string]string{"app": "shop"}}}
+	monitoring := mysql.NetworkPolicyPeer{NamespaceSelector: &meta_v1.LabelSelector{MatchLabels: map[string]string{namespaceNameLabel: "monitoring"}}}
+	f := newFixture(t, testMySql("db", withNetworkPolicy(shop), withSidecarNetworkPolicy(monitoring), withPodTemplate(mysql.PodTemplateSpec{
+		Containers: []v1.Container{{Name: "exporter", Image: "prom/mysqld-exporter", Ports: []v1.ContainerPort{{ContainerPort: 9104}}}},
+	})))
+	f.controller.config.NetworkPolicy.OperatorNamespace = "ops"
+	f.reconcile(testKey)
+
+	policy, err := f.kube.NetworkingV1().NetworkPolicies("default").Get(context.TODO(), "db", meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policy.Spec.Ingress) != 2 {
+		t.Fatalf("got %d ingress rules, want 2", len(policy.Spec.Ingress))
+	}
+	operator := f.controller.config.NetworkPolicy.operatorPeer()
+	want := [][]networkingv1.NetworkPolicyPeer{
+		{operator, {PodSelector: shop.PodSelector}},
+		{operator, {NamespaceSelector: monitoring.NamespaceSelector}},
+	}
+	for i, rule := range policy.Spec.Ingress {
+		if !equality.Semantic.DeepEqual(rule.From, want[i]) {
+			t.Errorf("got ingress[%d].from %v, want %v", i, rule.From, want[i])
+		}
+	}
+}
+
+// A replicated instance always allows one pod to be evicted, a single pod
+// follows the spec. The budget is owned by its MySql.
+func TestNewPDB(t *testing.T) {
+	zero := int32(0)
+	tests := []struct {
+		name           string
+		maxUnavailable *int32
+		replicas       int32
+		want           int
+	}{
+		{"default", nil, 1, 1},
+		{"blocked", &zero, 1, 0},
+		{"replicated", &zero, 3, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := testMySql("db")
+			s.Spec.DisruptionBudget.MaxUnavailable = test.maxUnavailable
+			pdb := newPDB(s, test.replicas, managedLabels(s, nil))
+			if got := pdb.Spec.MaxUnavailable.IntValue(); got != test.want {
+				t.Errorf("got maxUnavailable %d, want %d", got, test.want)
+			}
+			if len(pdb.OwnerReferences) != 1 || pdb.OwnerReferences[0].Kind != "MySql" || pdb.OwnerReferences[0].Name != "db" {
+				t.Errorf("got owner references %v, want the MySql db", pdb.OwnerReferences)
+			}
+		})
+	}
+}
+
+// Pods of a replicated instance prefer different nodes unless the spec gives
+// its own pod anti-affinity.
+func TestPodAffinity(t *testing.T) {
+	own := &v1.PodAntiAffinity{}
+	tests := []struct {
+		name     string
+		affinity *v1.Affinity
+		replicas int32
+		want     bool
+	}{
+		{"single", nil, 1, false},
+		{"replicated", nil, 3, true},
+		{"replicated with node affinity", &v1.Affinity{NodeAffinity: &v1.NodeAffinity{}}, 3, true},
+		{"replicated with own anti-affinity", &v1.Affinity{PodAntiAffinity: own}, 3, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			affinity := podAffinity(testMySql("db"), test.affinity, test.replicas)
+			got := affinity != nil && affinity.PodAntiAffinity != nil && affinity.PodAntiAffinity != own
+			if got != test.want {
+				t.Errorf("got default anti-affinity %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReconcileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        *mysql.MySql
+		verb     string
+		resource string
+		wantErr  string
+		// An event that must have been recorded.
+		wantEvent     string
+		wantPhase     mysql.MySqlPhase
+		wantFinalizer bool
+	}{
+		{
+			name:          "create deployment",
+			s:             testMySql("db"),
+			verb:          "create",
+			resource:      "deployments",
+			wantErr:       "injected",
+			wantEvent:     "Warning FailedCreate Failed to create Deployment db: injected",
+			wantPhase:     mysql.MySqlPhaseFailed,
+			wantFinalizer: true,
+		},
+		{
+			name:     "add finalizer",
+			s:        testMySql("db"),
+			verb:     "update",
+			resource: "mysqls",
+			wantErr:  "failed to add finalizer",
+		},
+		{
+			name:          "delete service",
+			s:             testMySql("db", withFinalizer, withDeletionTimestamp),
+			verb:          "delete",
+			resource:      "services",
+			wantErr:       "failed to delete service",
+			wantEvent:     "Warning FailedDelete Failed to delete service, deletion is blocked until it succeeds: injected",
+			wantPhase:     mysql.MySqlPhaseDeleting,
+			wantFinalizer: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFixture(t, test.s)
+			reactor := func(action k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, fmt.Errorf("injected")
+			}
+			if test.resource == "mysqls" {
+				f.mySqls.PrependReactor(test.verb, test.resource, reactor)
+			} else {
+				f.kube.PrependReactor(test.verb, test.resource, reactor)
+			}
+
+			err := f.controller.reconcile(testKey)
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("got error %v, want one containing %q", err, test.wantErr)
+			}
+			if test.wantEvent != "" {
+				found := false
+				events := f.events()
+				for _, event := range events {
+					found = found || event == test.wantEvent
+				}
+				if !found {
+					t.Errorf("got events %q, want %q among them", events, test.wantEvent)
+				}
+			}
+			s := f.getMySql("db")
+			if s.Status.Phase != test.wantPhase {
+				t.Errorf("got phase %q, want %q", s.Status.Phase, test.wantPhase)
+			}
+			if hasFinalizer(s) != test.wantFinalizer {
+				t.Errorf("got finalizers %q, want finalizer %t", s.Finalizers, test.wantFinalizer)
+			}
+		})
+	}
+}
+
+func TestProcessNextItem(t *testing.T) {
+	t.Run("reconciles", func(t *testing.T) {
+		f := newFixture(t, testMySql("db"))
+		f.controller.onAdd(testMySql("db"))
+		if !f.controller.processNextItem(0) {
+			t.Fatal("queue was shut down")
+		}
+		if n := f.controller.queue.NumRequeues(testKey); n != 0 {
+			t.Errorf("got %d requeues, want 0", n)
+		}
+		if _, err := f.kube.CoreV1().Services("default").Get(context.TODO(), "db", meta_v1.GetOptions{}); err != nil {
+			t.Errorf("service was not created. %+v", err)
+		}
+	})
+
+	t.Run("requeues on error", func(t *testing.T) {
+		f := newFixture(t, testMySql("db"))
+		f.kube.PrependReactor("create", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, fmt.Errorf("injected")
+		})
+		f.controller.onUpdate(testMySql("db"), testMySql("db"))
+		f.controller.processNextItem(0)
+		if n := f.controller.queue.NumRequeues(testKey); n != 1 {
+			t.Errorf("got %d requeues, want 1", n)
+		}
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		f := newFixture(t)
+		f.controller.onDelete(cache.DeletedFinalStateUnknown{Key: testKey, Obj: testMySql("db")})
+		f.controller.processNextItem(0)
+		if n := f.controller.queue.NumRequeues(testKey); n != 0 {
+			t.Errorf("got %d requeues, want 0", n)
+		}
+		checkList(t, "kube writes", f.kubeWrites(), nil)
+		checkList(t, "mysql writes", f.mySqlWrites(), nil)
+	})
+}
+
+func TestOwnedHandlers(t *testing.T) {
+	f := newFixture(t)
+	handlers := f.controller.ownedHandlers()
+	handlers.OnDelete(&v1.Service{ObjectMeta: meta_v1.ObjectMeta{Name: "db", Namespace: "default"}})
+	if n := f.controller.queue.Len(); n != 0 {
+		t.Fatalf("unmanaged object queued %d keys, want 0", n)
+	}
+
+	handlers.OnDelete(testPod("db-0", "db"))
+	key, _ := f.controller.queue.Get()
+	if key != testKey {
+		t.Errorf("got key %v, want %q", key, testKey)
+	}
+}
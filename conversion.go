@@ -0,0 +1,134 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1alpha1"
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	"go.uber.org/zap"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Path the CRD conversion webhook is served on.
+const convertPath = "/convert"
+
+// conversion tells the API server to convert MySql objects between versions
+// through this operator.
+func (w *webhookServer) conversion() *apiextensionsv1.CustomResourceConversion {
+	path := convertPath
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return &apiextensionsv1.CustomResourceConversion{
+		Strategy: apiextensionsv1.WebhookConverter,
+		Webhook: &apiextensionsv1.WebhookConversion{
+			ClientConfig: &apiextensionsv1.WebhookClientConfig{
+				Service: &apiextensionsv1.ServiceReference{
+					Namespace: w.config.ServiceNamespace,
+					Name:      w.config.ServiceName,
+					Path:      &path,
+				},
+				CABundle: w.caBundle,
+			},
+			ConversionReviewVersions: reviewVersions,
+		},
+	}
+}
+
+// conversionHandler answers ConversionReviews from the API server. v1 and
+// v1beta1 reviews have the same shape, and the response keeps the version of
+// the request.
+func conversionHandler(rw http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var review apiextensionsv1.ConversionReview
+	if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+		http.Error(rw, fmt.Sprintf("failed to decode conversion review: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	response := &apiextensionsv1.ConversionResponse{
+		UID:    review.Request.UID,
+		Result: meta_v1.Status{Status: meta_v1.StatusSuccess},
+	}
+	for _, obj := range review.Request.Objects {
+		converted, err := convertMySql(obj.Raw, review.Request.DesiredAPIVersion)
+		if err != nil {
+			log.Error("failed to convert mysql", zap.String("desiredAPIVersion", review.Request.DesiredAPIVersion), zap.Error(err))
+			response.ConvertedObjects = nil
+			response.Result = meta_v1.Status{Status: meta_v1.StatusFailure, Message: err.Error()}
+			break
+		}
+		response.ConvertedObjects = append(response.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+	review.Request = nil
+	review.Response = response
+
+	out, err := json.Marshal(review)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write(out)
+}
+
+// convertMySql converts a single encoded MySql to desiredAPIVersion.
+func convertMySql(raw []byte, desiredAPIVersion string) ([]byte, error) {
+	var typeMeta meta_v1.TypeMeta
+	if err := json.Unmarshal(raw, &typeMeta); err != nil {
+		return nil, fmt.Errorf("failed to decode object. %+v", err)
+	}
+	if typeMeta.APIVersion == desiredAPIVersion {
+		return raw, nil
+	}
+
+	alpha := v1alpha1.SchemeGroupVersion.String()
+	beta := mysql.SchemeGroupVersion.String()
+	switch {
+	case typeMeta.APIVersion == alpha && desiredAPIVersion == beta:
+		in := &v1alpha1.MySql{}
+		if err := json.Unmarshal(raw, in); err != nil {
+			return nil, fmt.Errorf("failed to decode %s MySql. %+v", alpha, err)
+		}
+		out := &mysql.MySql{}
+		if err := mysql.ConvertFromV1alpha1(in, out); err != nil {
+			return nil, err
+		}
+		return json.Marshal(out)
+	case typeMeta.APIVersion == beta && desiredAPIVersion == alpha:
+		in := &mysql.MySql{}
+		if err := json.Unmarshal(raw, in); err != nil {
+			return nil, fmt.Errorf("failed to decode %s MySql. %+v", beta, err)
+		}
+		out := &v1alpha1.MySql{}
+		if err := mysql.ConvertToV1alpha1(in, out); err != nil {
+			return nil, err
+		}
+		return json.Marshal(out)
+	}
+	return nil, fmt.Errorf("cannot convert MySql from %s to %s", typeMeta.APIVersion, desiredAPIVersion)
+}
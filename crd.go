@@ -0,0 +1,88 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	"go.uber.org/zap"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+// ensureCRD creates the MySql CustomResourceDefinition, or brings an existing
+// one up to date with the schema compiled into the operator, and waits for
+// the API server to start serving it. Without a conversion webhook objects
+// are only relabelled when read through another version.
+func ensureCRD(cluster *clusterContext, conversion *apiextensionsv1.CustomResourceConversion) error {
+	desired, err := mysql.CustomResourceDefinition()
+	if err != nil {
+		return fmt.Errorf("failed to decode the mysql CRD. %+v", err)
+	}
+	if conversion == nil {
+		log.Warn("no conversion webhook, MySql objects stored as v1alpha1 will not be converted")
+		conversion = &apiextensionsv1.CustomResourceConversion{Strategy: apiextensionsv1.NoneConverter}
+	}
+	desired.Spec.Conversion = conversion
+	crds := cluster.APIExtensionClientset.ApiextensionsV1().CustomResourceDefinitions()
+
+	// Several replicas starting at once may race to update the CRD.
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := crds.Get(context.TODO(), desired.Name, meta_v1.GetOptions{})
+		if errors.IsNotFound(err) {
+			log.Info("creating CRD", zap.String("name", desired.Name))
+			_, err = crds.Create(context.TODO(), desired, meta_v1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		log.Info("updating CRD", zap.String("name", desired.Name))
+		crd := existing.DeepCopy()
+		crd.Spec = desired.Spec
+		_, err = crds.Update(context.TODO(), crd, meta_v1.UpdateOptions{})
+		return err
+	})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create or update CRD %s. %+v", desired.Name, err)
+	}
+
+	return wait.Poll(cluster.Interval, cluster.Timeout, func() (bool, error) {
+		crd, err := crds.Get(context.TODO(), desired.Name, meta_v1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, cond := range crd.Status.Conditions {
+			switch cond.Type {
+			case apiextensionsv1.Established:
+				if cond.Status == apiextensionsv1.ConditionTrue {
+					return true, nil
+				}
+			case apiextensionsv1.NamesAccepted:
+				if cond.Status == apiextensionsv1.ConditionFalse {
+					return false, fmt.Errorf("CRD %s names were not accepted. %s", desired.Name, cond.Message)
+				}
+			}
+		}
+		return false, nil
+	})
+}
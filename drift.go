@@ -0,0 +1,484 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The functions below compare a live object backing a MySql with the one the
+// operator would create for it. Every owned field that differs is copied onto
+// live and its path returned. Fields the operator does not set, including
+// those defaulted by the API server, are left alone.
+
+// Put back the entries of desired, such as labels. Other entries are kept.
+func mapDrift(live *map[string]string, desired map[string]string) bool {
+	drifted := false
+	for k, v := range desired {
+		if got, ok := (*live)[k]; ok && got == v {
+			continue
+		}
+		if *live == nil {
+			*live = map[string]string{}
+		}
+		(*live)[k] = v
+		drifted = true
+	}
+	return drifted
+}
+
+// Keys of the annotations the operator put on an object, so that those
+// removed from the MySql are removed from the object too.
+const managedAnnotationsAnnotation = "myproject.io/managed-annotations"
+
+// The annotations to put on an object, along with the list of their keys.
+func managedAnnotations(annotations map[string]string) map[string]string {
+	if len(annotations) == 0 {
+		return nil
+	}
+	managed := map[string]string{}
+	var keys []string
+	for k, v := range annotations {
+		managed[k] = v
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	managed[managedAnnotationsAnnotation] = strings.Join(keys, ",")
+	return managed
+}
+
+// Put back the annotations in desired and remove those the operator set
+// earlier that are no longer wanted. Other annotations are kept.
+func annotationsDrift(live *map[string]string, desired map[string]string) bool {
+	drifted := false
+	if previous, ok := (*live)[managedAnnotationsAnnotation]; ok {
+		for _, k := range append(strings.Split(previous, ","), managedAnnotationsAnnotation) {
+			if _, wanted := desired[k]; wanted {
+				continue
+			}
+			if _, ok := (*live)[k]; ok {
+				delete(*live, k)
+				drifted = true
+			}
+		}
+	}
+	return mapDrift(live, desired) || drifted
+}
+
+// Switching between a headless Service and one with a cluster IP cannot be
+// done in place.
+func serviceNeedsRecreate(live, desired *v1.Service) bool {
+	return (live.Spec.ClusterIP == v1.ClusterIPNone) != (desired.Spec.ClusterIP == v1.ClusterIPNone)
+}
+
+// The selector of a Deployment is immutable, one selecting the pods of every
+// MySql in the namespace can only be replaced by recreating the Deployment.
+func deploymentNeedsRecreate(live, desired *appsv1.Deployment) bool {
+	return !equality.Semantic.DeepEqual(live.Spec.Selector, desired.Spec.Selector)
+}
+
+func serviceDrift(live, desired *v1.Service) []string {
+	var drifted []string
+	if mapDrift(&live.Labels, desired.Labels) {
+		drifted = append(drifted, "metadata.labels")
+	}
+	if annotationsDrift(&live.Annotations, desired.Annotations) {
+		drifted = append(drifted, "metadata.annotations")
+	}
+	if live.Spec.Type != desired.Spec.Type {
+		live.Spec.Type = desired.Spec.Type
+		drifted = append(drifted, "spec.type")
+	}
+	if !equality.Semantic.DeepEqual(live.Spec.Selector, desired.Spec.Selector) {
+		live.Spec.Selector = desired.Spec.Selector
+		drifted = append(drifted, "spec.selector")
+	}
+	if !servicePortsMatch(live.Spec.Ports, desired.Spec.Ports) {
+		live.Spec.Ports = mergeServicePorts(live.Spec.Ports, desired.Spec.Ports)
+		drifted = append(drifted, "spec.ports")
+	}
+	if live.Spec.ExternalTrafficPolicy != desired.Spec.ExternalTrafficPolicy {
+		live.Spec.ExternalTrafficPolicy = desired.Spec.ExternalTrafficPolicy
+		drifted = append(drifted, "spec.externalTrafficPolicy")
+	}
+	if !equality.Semantic.DeepEqual(live.Spec.LoadBalancerSourceRanges, desired.Spec.LoadBalancerSourceRanges) {
+		live.Spec.LoadBalancerSourceRanges = desired.Spec.LoadBalancerSourceRanges
+		drifted = append(drifted, "spec.loadBalancerSourceRanges")
+	}
+	return drifted
+}
+
+// The protocol of a port is defaulted, and its node port allocated unless
+// the MySql asks for one.
+func servicePortsMatch(live, desired []v1.ServicePort) bool {
+	if len(live) != len(desired) {
+		return false
+	}
+	for i, want := range desired {
+		got := live[i]
+		if got.Name != want.Name || got.Port != want.Port || got.TargetPort != want.TargetPort {
+			return false
+		}
+		if want.NodePort != 0 && got.NodePort != want.NodePort {
+			return false
+		}
+	}
+	return true
+}
+
+// The desired ports, keeping node ports already allocated to them.
+func mergeServicePorts(live, desired []v1.ServicePort) []v1.ServicePort {
+	merged := make([]v1.ServicePort, len(desired))
+	for i, want := range desired {
+		merged[i] = want
+		if want.NodePort != 0 {
+			continue
+		}
+		for _, got := range live {
+			if got.Port == want.Port {
+				merged[i].NodePort = got.NodePort
+			}
+		}
+	}
+	return merged
+}
+
+// Most of a claim's spec cannot change once it is bound, and its size is kept
+// in line by resizePVC.
+func pvcDrift(live, desired *v1.PersistentVolumeClaim) []string {
+	var drifted []string
+	if mapDrift(&live.Labels, desired.Labels) {
+		drifted = append(drifted, "metadata.labels")
+	}
+	return drifted
+}
+
+func deploymentDrift(live, desired *appsv1.Deployment) []string {
+	var drifted []string
+	if mapDrift(&live.Labels, desired.Labels) {
+		drifted = append(drifted, "metadata.labels")
+	}
+	if replicas(live.Spec.Replicas) != replicas(desired.Spec.Replicas) {
+		live.Spec.Replicas = desired.Spec.Replicas
+		drifted = append(drifted, "spec.replicas")
+	}
+	if live.Spec.Strategy.Type != desired.Spec.Strategy.Type {
+		live.Spec.Strategy = desired.Spec.Strategy
+		drifted = append(drifted, "spec.strategy")
+	}
+	if mapDrift(&live.Spec.Template.Labels, desired.Spec.Template.Labels) {
+		drifted = append(drifted, "spec.template.metadata.labels")
+	}
+	if live.Spec.Template.Annotations[podTemplateHashAnnotation] != desired.Spec.Template.Annotations[podTemplateHashAnnotation] {
+		// spec.podTemplate changed, what it added before may have to go.
+		live.Spec.Template.Spec = desired.Spec.Template.Spec
+		drifted = append(drifted, "spec.template.spec")
+	}
+	annotationsDrifted := mapDrift(&live.Spec.Template.Annotations, desired.Spec.Template.Annotations)
+	if _, ok := live.Spec.Template.Annotations[v1.SeccompPodAnnotationKey]; ok {
+		// Put there by earlier releases. The security contexts carry the
+		// profile now, and a stale annotation would contradict them.
+		delete(live.Spec.Template.Annotations, v1.SeccompPodAnnotationKey)
+		annotationsDrifted = true
+	}
+	if annotationsDrifted {
+		drifted = append(drifted, "spec.template.metadata.annotations")
+	}
+	drifted = append(drifted, podSpecDrift(&live.Spec.Template.Spec, &desired.Spec.Template.Spec, "spec.template.spec")...)
+	return drifted
+}
+
+func pdbDrift(live, desired *policyv1.PodDisruptionBudget) []string {
+	var drifted []string
+	if mapDrift(&live.Labels, desired.Labels) {
+		drifted = append(drifted, "metadata.labels")
+	}
+	if !equality.Semantic.DeepEqual(live.OwnerReferences, desired.OwnerReferences) {
+		live.OwnerReferences = desired.OwnerReferences
+		drifted = append(drifted, "metadata.ownerReferences")
+	}
+	if !equality.Semantic.DeepEqual(live.Spec.MaxUnavailable, desired.Spec.MaxUnavailable) || live.Spec.MinAvailable != nil {
+		live.Spec.MaxUnavailable = desired.Spec.MaxUnavailable
+		live.Spec.MinAvailable = nil
+		drifted = append(drifted, "spec.maxUnavailable")
+	}
+	if !equality.Semantic.DeepEqual(live.Spec.Selector, desired.Spec.Selector) {
+		live.Spec.Selector = desired.Spec.Selector
+		drifted = append(drifted, "spec.selector")
+	}
+	return drifted
+}
+
+func networkPolicyDrift(live, desired *networkingv1.NetworkPolicy) []string {
+	var drifted []string
+	if mapDrift(&live.Labels, desired.Labels) {
+		drifted = append(drifted, "metadata.labels")
+	}
+	if !equality.Semantic.DeepEqual(live.Spec.PodSelector, desired.Spec.PodSelector) {
+		live.Spec.PodSelector = desired.Spec.PodSelector
+		drifted = append(drifted, "spec.podSelector")
+	}
+	if !equality.Semantic.DeepEqual(live.Spec.Ingress, desired.Spec.Ingress) {
+		live.Spec.Ingress = desired.Spec.Ingress
+		drifted = append(drifted, "spec.ingress")
+	}
+	if !equality.Semantic.DeepEqual(live.Spec.Egress, desired.Spec.Egress) {
+		live.Spec.Egress = desired.Spec.Egress
+		drifted = append(drifted, "spec.egress")
+	}
+	if !equality.Semantic.DeepEqual(live.Spec.PolicyTypes, desired.Spec.PolicyTypes) {
+		live.Spec.PolicyTypes = desired.Spec.PolicyTypes
+		drifted = append(drifted, "spec.policyTypes")
+	}
+	return drifted
+}
+
+func replicas(r *int32) int32 {
+	if r == nil {
+		return 1
+	}
+	return *r
+}
+
+// Containers and volumes are matched by name, so ones added by someone else
+// are kept.
+func podSpecDrift(live, desired *v1.PodSpec, path string) []string {
+	var drifted []string
+	for _, want := range desired.Containers {
+		containerPath := fmt.Sprintf("%s.containers[%s]", path, want.Name)
+		i := containerIndex(live.Containers, want.Name)
+		if i < 0 {
+			live.Containers = append(live.Containers, want)
+			drifted = append(drifted, containerPath)
+			continue
+		}
+		drifted = append(drifted, containerDrift(&live.Containers[i], &want, containerPath)...)
+	}
+	for _, want := range desired.InitContainers {
+		containerPath := fmt.Sprintf("%s.initContainers[%s]", path, want.Name)
+		i := containerIndex(live.InitContainers, want.Name)
+		if i < 0 {
+			live.InitContainers = append(live.InitContainers, want)
+			drifted = append(drifted, containerPath)
+			continue
+		}
+		drifted = append(drifted, containerDrift(&live.InitContainers[i], &want, containerPath)...)
+	}
+	for _, want := range desired.Volumes {
+		i := volumeIndex(live.Volumes, want.Name)
+		if i < 0 {
+			live.Volumes = append(live.Volumes, want)
+		} else if !equality.Semantic.DeepEqual(live.Volumes[i].VolumeSource, want.VolumeSource) {
+			live.Volumes[i] = want
+		} else {
+			continue
+		}
+		drifted = append(drifted, fmt.Sprintf("%s.volumes[%s]", path, want.Name))
+	}
+	if !equality.Semantic.DeepEqual(live.Affinity, desired.Affinity) {
+		live.Affinity = desired.Affinity
+		drifted = append(drifted, path+".affinity")
+	}
+	if !equality.Semantic.DeepEqual(live.NodeSelector, desired.NodeSelector) {
+		live.NodeSelector = desired.NodeSelector
+		drifted = append(drifted, path+".nodeSelector")
+	}
+	if !equality.Semantic.DeepEqual(live.Tolerations, desired.Tolerations) {
+		live.Tolerations = desired.Tolerations
+		drifted = append(drifted, path+".tolerations")
+	}
+	if !equality.Semantic.DeepEqual(live.TopologySpreadConstraints, desired.TopologySpreadConstraints) {
+		live.TopologySpreadConstraints = desired.TopologySpreadConstraints
+		drifted = append(drifted, path+".topologySpreadConstraints")
+	}
+	if !equality.Semantic.DeepEqual(live.SecurityContext, desired.SecurityContext) {
+		live.SecurityContext = desired.SecurityContext
+		drifted = append(drifted, path+".securityContext")
+	}
+	if live.PriorityClassName != desired.PriorityClassName {
+		live.PriorityClassName = desired.PriorityClassName
+		drifted = append(drifted, path+".priorityClassName")
+	}
+	if !equality.Semantic.DeepEqual(live.RuntimeClassName, desired.RuntimeClassName) {
+		live.RuntimeClassName = desired.RuntimeClassName
+		drifted = append(drifted, path+".runtimeClassName")
+	}
+	return drifted
+}
+
+func containerDrift(live, desired *v1.Container, path string) []string {
+	var drifted []string
+	if live.Image != desired.Image {
+		live.Image = desired.Image
+		drifted = append(drifted, path+".image")
+	}
+	if !equality.Semantic.DeepEqual(live.Command, desired.Command) {
+		live.Command = desired.Command
+		drifted = append(drifted, path+".command")
+	}
+	if !equality.Semantic.DeepEqual(live.Args, desired.Args) {
+		live.Args = desired.Args
+		drifted = append(drifted, path+".args")
+	}
+	if !equality.Semantic.DeepEqual(live.Env, desired.Env) {
+		live.Env = desired.Env
+		drifted = append(drifted, path+".env")
+	}
+	if !containerPortsMatch(live.Ports, desired.Ports) {
+		live.Ports = desired.Ports
+		drifted = append(drifted, path+".ports")
+	}
+	if !equality.Semantic.DeepEqual(live.VolumeMounts, desired.VolumeMounts) {
+		live.VolumeMounts = desired.VolumeMounts
+		drifted = append(drifted, path+".volumeMounts")
+	}
+	if !equality.Semantic.DeepEqual(live.SecurityContext, desired.SecurityContext) {
+		live.SecurityContext = desired.SecurityContext
+		drifted = append(drifted, path+".securityContext")
+	}
+	if !equality.Semantic.DeepEqual(live.Resources, desired.Resources) {
+		live.Resources = desired.Resources
+		drifted = append(drifted, path+".resources")
+	}
+	return drifted
+}
+
+// The protocol of a port is defaulted.
+func containerPortsMatch(live, desired []v1.ContainerPort) bool {
+	if len(live) != len(desired) {
+		return false
+	}
+	for i := range desired {
+		if live[i].Name != desired[i].Name || live[i].ContainerPort != desired[i].ContainerPort {
+			return false
+		}
+	}
+	return true
+}
+
+func containerIndex(containers []v1.Container, name string) int {
+	for i := range containers {
+		if containers[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func volumeIndex(volumes []v1.Volume, name string) int {
+	for i := range volumes {
+		if volumes[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Record the correction of drifted fields, once update has written them back.
+func (c *MySqlController) correctDrift(log *zap.Logger, s *mysql.MySql, kind string, name string, drifted []string, update func() error) error {
+	if len(drifted) == 0 {
+		return nil
+	}
+	if err := update(); err != nil {
+		return fmt.Errorf("failed to correct drift of %s %s. %+v", kind, name, err)
+	}
+	driftCorrections.WithLabelValues(kind).Inc()
+	log.Info("corrected drift", zap.String("kind", kind), zap.String("name", name), zap.Strings("fields", drifted))
+	c.recorder.Eventf(s, v1.EventTypeNormal, reasonDriftCorrected, "Corrected changes made to %s %s: %s", kind, name, strings.Join(drifted, ", "))
+	return nil
+}
+
+func (c *MySqlController) syncService(log *zap.Logger, s *mysql.MySql, live *v1.Service, desired *v1.Service) error {
+	if serviceNeedsRecreate(live, desired) {
+		// The Service is created again once its deletion is observed.
+		log.Info("recreating service", zap.String("service", live.Name), zap.String("type", string(desired.Spec.Type)))
+		err := c.clientset.CoreV1().Services(live.Namespace).Delete(context.TODO(), live.Name, meta_v1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete service to recreate it. %+v", err)
+		}
+		c.recorder.Eventf(s, v1.EventTypeNormal, reasonRecreating, "Recreating Service %s as type %s", live.Name, desired.Spec.Type)
+		return nil
+	}
+
+	live = live.DeepCopy()
+	return c.correctDrift(log, s, "Service", live.Name, serviceDrift(live, desired), func() error {
+		_, err := c.clientset.CoreV1().Services(live.Namespace).Update(context.TODO(), live, meta_v1.UpdateOptions{})
+		return err
+	})
+}
+
+// Returns the claim as it is after any correction.
+func (c *MySqlController) syncPVC(log *zap.Logger, s *mysql.MySql, live *v1.PersistentVolumeClaim, desired *v1.PersistentVolumeClaim) (*v1.PersistentVolumeClaim, error) {
+	updated := live.DeepCopy()
+	err := c.correctDrift(log, s, "PersistentVolumeClaim", live.Name, pvcDrift(updated, desired), func() error {
+		var err error
+		updated, err = c.clientset.CoreV1().PersistentVolumeClaims(live.Namespace).Update(context.TODO(), updated, meta_v1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (c *MySqlController) syncDeployment(log *zap.Logger, s *mysql.MySql, live *appsv1.Deployment, desired *appsv1.Deployment) error {
+	if deploymentNeedsRecreate(live, desired) {
+		// The Deployment is created again once its deletion is observed.
+		log.Info("recreating deployment", zap.String("deployment", live.Name))
+		err := c.clientset.AppsV1().Deployments(live.Namespace).Delete(context.TODO(), live.Name, meta_v1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete deployment to recreate it. %+v", err)
+		}
+		c.recorder.Eventf(s, v1.EventTypeNormal, reasonRecreating, "Recreating Deployment %s to change its selector", live.Name)
+		return nil
+	}
+
+	live = live.DeepCopy()
+	return c.correctDrift(log, s, "Deployment", live.Name, deploymentDrift(live, desired), func() error {
+		_, err := c.clientset.AppsV1().Deployments(live.Namespace).Update(context.TODO(), live, meta_v1.UpdateOptions{})
+		return err
+	})
+}
+
+func (c *MySqlController) syncPDB(log *zap.Logger, s *mysql.MySql, live *policyv1.PodDisruptionBudget, desired *policyv1.PodDisruptionBudget) error {
+	live = live.DeepCopy()
+	return c.correctDrift(log, s, "PodDisruptionBudget", live.Name, pdbDrift(live, desired), func() error {
+		_, err := c.clientset.PolicyV1().PodDisruptionBudgets(live.Namespace).Update(context.TODO(), live, meta_v1.UpdateOptions{})
+		return err
+	})
+}
+
+func (c *MySqlController) syncNetworkPolicy(log *zap.Logger, s *mysql.MySql, live *networkingv1.NetworkPolicy, desired *networkingv1.NetworkPolicy) error {
+	live = live.DeepCopy()
+	return c.correctDrift(log, s, "NetworkPolicy", live.Name, networkPolicyDrift(live, desired), func() error {
+		_, err := c.clientset.NetworkingV1().NetworkPolicies(live.Namespace).Update(context.TODO(), live, meta_v1.UpdateOptions{})
+		return err
+	})
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	mysqlscheme "github.com/tonya11en/mysql-operator/pkg/client/clientset/versioned/scheme"
+	"go.uber.org/zap"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// Reasons attached to the Events recorded on MySql objects.
+const (
+	reasonCreated      = "Created"
+	reasonFailedCreate = "FailedCreate"
+	reasonPVCPending   = "PVCPending"
+	reasonRunning      = "Running"
+	reasonFailedDelete = "FailedDelete"
+	reasonDeleted      = "Deleted"
+	reasonResized      = "Resized"
+	reasonFailedResize = "FailedResize"
+	// Fields of an object backing the MySql were changed and put back.
+	reasonDriftCorrected = "DriftCorrected"
+	reasonRecreating     = "Recreating"
+	reasonCertRotated    = "CertificateRotated"
+	reasonPaused         = "Paused"
+	reasonResumed        = "Resumed"
+)
+
+// Identical events for the same object are only sent once per window, so an
+// instance that fails every reconcile does not flood the API server.
+const eventDedupWindow = 10 * time.Minute
+
+// Name the operator's events are attributed to.
+const eventComponent = "mysql-operator"
+
+// newEventRecorder returns a recorder that writes deduplicated events to the
+// API server.
+func newEventRecorder(clientset kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartLogging(func(format string, args ...interface{}) {
+		log.Debug(fmt.Sprintf(format, args...))
+	})
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+	recorder := broadcaster.NewRecorder(mysqlscheme.Scheme, v1.EventSource{Component: eventComponent})
+	return newDedupRecorder(recorder, eventDedupWindow)
+}
+
+// dedupRecorder drops events identical to one already sent for the same
+// object within the window. The broadcaster's own correlator only aggregates
+// events once they reach the API server, this keeps them from being sent.
+type dedupRecorder struct {
+	record.EventRecorder
+
+	window time.Duration
+	lock   sync.Mutex
+	sent   map[string]time.Time
+}
+
+func newDedupRecorder(recorder record.EventRecorder, window time.Duration) *dedupRecorder {
+	return &dedupRecorder{
+		EventRecorder: recorder,
+		window:        window,
+		sent:          map[string]time.Time{},
+	}
+}
+
+// shouldSend reports whether an event has not been sent within the window,
+// and remembers it if so.
+func (r *dedupRecorder) shouldSend(object runtime.Object, eventtype, reason, message string) bool {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return true
+	}
+	key := fmt.Sprintf("%s/%s/%s/%s/%s/%s", accessor.GetNamespace(), accessor.GetName(), accessor.GetUID(), eventtype, reason, message)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	if last, ok := r.sent[key]; ok && now.Sub(last) < r.window {
+		log.Debug("suppressing duplicate event", zap.String("reason", reason), zap.String("message", message))
+		return false
+	}
+	for k, last := range r.sent {
+		if now.Sub(last) >= r.window {
+			delete(r.sent, k)
+		}
+	}
+	r.sent[key] = now
+	return true
+}
+
+func (r *dedupRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if r.shouldSend(object, eventtype, reason, message) {
+		r.EventRecorder.Event(object, eventtype, reason, message)
+	}
+}
+
+func (r *dedupRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *dedupRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	if r.shouldSend(object, eventtype, reason, message) {
+		r.EventRecorder.AnnotatedEventf(object, annotations, eventtype, reason, "%s", message)
+	}
+}
@@ -0,0 +1,332 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	"go.uber.org/zap"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// The finalizer keeps a deleted MySql around until its deletion policy has
+// been carried out.
+const mySqlFinalizer = "myproject.io/mysql-cleanup"
+
+// While deletion protection is on the claim carries this finalizer, so it
+// survives a delete that slipped past the webhook.
+const pvcProtectionFinalizer = "myproject.io/deletion-protection"
+
+// Setting this annotation to "true" has the same effect as
+// spec.deletionProtection.
+const deletionProtectionAnnotation = "myproject.io/deletion-protection"
+
+// Labels put on a PersistentVolumeClaim retained after its MySql was deleted.
+// A new MySql with the same name adopts the claim and removes them.
+const (
+	retainedLabel         = "myproject.io/retained"
+	retainedInstanceLabel = "myproject.io/instance"
+)
+
+// Reasons for the events recorded while carrying out a deletion policy.
+const (
+	reasonRetained        = "Retained"
+	reasonAdopted         = "Adopted"
+	reasonSnapshotCreated = "SnapshotCreated"
+	reasonSnapshotPending = "SnapshotPending"
+	reasonDeleteBlocked   = "DeleteBlocked"
+)
+
+// The GA VolumeSnapshot API, served by the CSI external-snapshotter 4.0 and
+// later.
+var volumeSnapshotResource = schema.GroupVersionResource{
+	Group:    "snapshot.storage.k8s.io",
+	Version:  "v1",
+	Resource: "volumesnapshots",
+}
+
+// errSnapshotPending is returned while the final snapshot of a volume is not
+// ready yet, so the MySql is requeued and the finalizer kept in place.
+var errSnapshotPending = fmt.Errorf("waiting for volume snapshot to become ready")
+
+func hasFinalizer(s *mysql.MySql) bool {
+	for _, f := range s.Finalizers {
+		if f == mySqlFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// Add the finalizer to a MySql that does not have it yet, returning the
+// updated object.
+func (c *MySqlController) ensureFinalizer(log *zap.Logger, s *mysql.MySql) (*mysql.MySql, error) {
+	if hasFinalizer(s) {
+		return s, nil
+	}
+
+	s = s.DeepCopy()
+	s.Finalizers = append(s.Finalizers, mySqlFinalizer)
+	updated, err := c.mySqlClientset.MyprojectV1beta1().MySqls(s.Namespace).Update(context.TODO(), s, meta_v1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add finalizer. %+v", err)
+	}
+	log.Debug("added finalizer")
+	return updated, nil
+}
+
+func (c *MySqlController) removeFinalizer(log *zap.Logger, s *mysql.MySql) error {
+	// Status may have been written since s was read, so start from the latest.
+	s, err := c.mySqlClientset.MyprojectV1beta1().MySqls(s.Namespace).Get(context.TODO(), s.Name, meta_v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var finalizers []string
+	for _, f := range s.Finalizers {
+		if f != mySqlFinalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	s.Finalizers = finalizers
+	_, err = c.mySqlClientset.MyprojectV1beta1().MySqls(s.Namespace).Update(context.TODO(), s, meta_v1.UpdateOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove finalizer. %+v", err)
+	}
+	log.Info("removed finalizer")
+	return nil
+}
+
+func deletionProtected(s *mysql.MySql) bool {
+	return s.Spec.DeletionProtection || s.Annotations[deletionProtectionAnnotation] == "true"
+}
+
+// Add or remove the protection finalizer on the claim of a MySql.
+func (c *MySqlController) syncPVCProtection(log *zap.Logger, s *mysql.MySql, pvc *v1.PersistentVolumeClaim, protect bool) error {
+	has := false
+	var finalizers []string
+	for _, f := range pvc.Finalizers {
+		if f == pvcProtectionFinalizer {
+			has = true
+			continue
+		}
+		finalizers = append(finalizers, f)
+	}
+	if has == protect {
+		return nil
+	}
+
+	pvc = pvc.DeepCopy()
+	if protect {
+		finalizers = append(finalizers, pvcProtectionFinalizer)
+	}
+	pvc.Finalizers = finalizers
+	if _, err := c.clientset.CoreV1().PersistentVolumeClaims(pvc.Namespace).Update(context.TODO(), pvc, meta_v1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update deletion protection of pvc. %+v", err)
+	}
+	log.Info("updated deletion protection of pvc", zap.String("pvc", pvc.Name), zap.Bool("protected", protect))
+	return nil
+}
+
+func deletionPolicy(s *mysql.MySql) mysql.DeletionPolicy {
+	if s.Spec.DeletionPolicy == "" {
+		return mysql.DeletionPolicyDelete
+	}
+	return s.Spec.DeletionPolicy
+}
+
+// Carry out the deletion policy of a MySql that is being deleted, and release
+// it once that is done.
+func (c *MySqlController) finalize(log *zap.Logger, key string, s *mysql.MySql) error {
+	if !hasFinalizer(s) {
+		return nil
+	}
+
+	if deletionProtected(s) {
+		// Nothing happens until the flag is cleared, which triggers another
+		// reconcile.
+		c.setPhase(log, key, s, mysql.MySqlPhaseDeleting, "deletion is blocked by deletion protection")
+		c.recorder.Eventf(s, v1.EventTypeWarning, reasonDeleteBlocked,
+			"Deletion is blocked because deletion protection is enabled; set spec.deletionProtection to false and remove the %s annotation to let it proceed", deletionProtectionAnnotation)
+		return nil
+	}
+
+	// Release the claim so the deletion policy can act on it.
+	pvc, err := c.cacheFor(s.Namespace).pvcs.PersistentVolumeClaims(s.Namespace).Get(getPvcName(s.Name))
+	if err == nil {
+		err = c.syncPVCProtection(log, s, pvc, false)
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	policy := deletionPolicy(s)
+	log = log.With(zap.String("deletionPolicy", string(policy)))
+	c.setPhase(log, key, s, mysql.MySqlPhaseDeleting, fmt.Sprintf("applying deletion policy %s", policy))
+
+	switch policy {
+	case mysql.DeletionPolicyDelete:
+		err = c.cleanup(log, s, true)
+	case mysql.DeletionPolicyRetain:
+		err = c.retainPVC(log, s)
+		if err == nil {
+			err = c.cleanup(log, s, false)
+		}
+	case mysql.DeletionPolicySnapshot:
+		err = c.snapshotPVC(log, s)
+		if err == nil {
+			err = c.cleanup(log, s, true)
+		}
+	default:
+		err = fmt.Errorf("unknown deletion policy %q", policy)
+		c.recorder.Eventf(s, v1.EventTypeWarning, reasonDeleteBlocked, "Deletion is blocked: %v", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := c.removeFinalizer(log, s); err != nil {
+		return err
+	}
+	c.phases.set(key, "")
+	return nil
+}
+
+// Label the claim of a MySql so it survives the deletion and can be adopted
+// by a new MySql of the same name.
+func (c *MySqlController) retainPVC(log *zap.Logger, s *mysql.MySql) error {
+	pvc, err := c.cacheFor(s.Namespace).pvcs.PersistentVolumeClaims(s.Namespace).Get(getPvcName(s.Name))
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if pvc.Labels[retainedLabel] == "true" {
+		return nil
+	}
+
+	pvc = pvc.DeepCopy()
+	if pvc.Labels == nil {
+		pvc.Labels = map[string]string{}
+	}
+	pvc.Labels[retainedLabel] = "true"
+	pvc.Labels[retainedInstanceLabel] = s.Name
+	if _, err := c.clientset.CoreV1().PersistentVolumeClaims(s.Namespace).Update(context.TODO(), pvc, meta_v1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to label retained pvc. %+v", err)
+	}
+
+	log.Info("retained pvc", zap.String("pvc", pvc.Name))
+	c.recorder.Eventf(s, v1.EventTypeNormal, reasonRetained, "Retained PersistentVolumeClaim %s", pvc.Name)
+	return nil
+}
+
+// Adopt a claim retained from an earlier MySql of the same name.
+func (c *MySqlController) adoptPVC(log *zap.Logger, s *mysql.MySql, pvc *v1.PersistentVolumeClaim) error {
+	if pvc.Labels[retainedLabel] != "true" || pvc.Labels[retainedInstanceLabel] != s.Name {
+		return nil
+	}
+
+	pvc = pvc.DeepCopy()
+	delete(pvc.Labels, retainedLabel)
+	delete(pvc.Labels, retainedInstanceLabel)
+	if _, err := c.clientset.CoreV1().PersistentVolumeClaims(s.Namespace).Update(context.TODO(), pvc, meta_v1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to adopt retained pvc. %+v", err)
+	}
+
+	log.Info("adopted retained pvc", zap.String("pvc", pvc.Name))
+	c.recorder.Eventf(s, v1.EventTypeNormal, reasonAdopted, "Adopted retained PersistentVolumeClaim %s", pvc.Name)
+	return nil
+}
+
+// The name of the snapshot taken of a MySql's claim before it is deleted. The
+// UID keeps snapshots of successive instances with the same name apart.
+func getSnapshotName(s *mysql.MySql) string {
+	uid := string(s.UID)
+	if len(uid) > 8 {
+		uid = uid[:8]
+	}
+	return fmt.Sprintf("%s-final-%s", getPvcName(s.Name), uid)
+}
+
+// Take a VolumeSnapshot of the claim of a MySql, returning errSnapshotPending
+// until it is ready to use.
+func (c *MySqlController) snapshotPVC(log *zap.Logger, s *mysql.MySql) error {
+	_, err := c.cacheFor(s.Namespace).pvcs.PersistentVolumeClaims(s.Namespace).Get(getPvcName(s.Name))
+	if errors.IsNotFound(err) {
+		// Nothing left to snapshot.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	snapshots := c.dynamicClient.Resource(volumeSnapshotResource).Namespace(s.Namespace)
+	name := getSnapshotName(s)
+	snapshot, err := snapshots.Get(context.TODO(), name, meta_v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		spec := map[string]interface{}{
+			"source": map[string]interface{}{
+				"persistentVolumeClaimName": getPvcName(s.Name),
+			},
+		}
+		if class := c.config.Defaults.VolumeSnapshotClass; class != "" {
+			spec["volumeSnapshotClassName"] = class
+		}
+		snapshot = &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": volumeSnapshotResource.GroupVersion().String(),
+			"kind":       "VolumeSnapshot",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": s.Namespace,
+				"labels": map[string]interface{}{
+					retainedInstanceLabel: s.Name,
+				},
+			},
+			"spec": spec,
+		}}
+		snapshot, err = snapshots.Create(context.TODO(), snapshot, meta_v1.CreateOptions{})
+		if err != nil {
+			c.recorder.Eventf(s, v1.EventTypeWarning, reasonDeleteBlocked, "Deletion is blocked, failed to create VolumeSnapshot %s: %v", name, err)
+			return fmt.Errorf("failed to create volume snapshot. %+v", err)
+		}
+		log.Info("created volume snapshot", zap.String("snapshot", name))
+		c.recorder.Eventf(s, v1.EventTypeNormal, reasonSnapshotCreated, "Created VolumeSnapshot %s", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	ready, _, _ := unstructured.NestedBool(snapshot.Object, "status", "readyToUse")
+	if !ready {
+		c.recorder.Eventf(s, v1.EventTypeNormal, reasonSnapshotPending, "Waiting for VolumeSnapshot %s to become ready before deleting the volume", name)
+		return errSnapshotPending
+	}
+	return nil
+}
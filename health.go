@@ -0,0 +1,203 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/client-go/tools/cache"
+)
+
+// A worker that has been busy with one key for longer than this is considered
+// wedged, and the operator reports itself as not alive.
+const workerStuckThreshold = 5 * time.Minute
+
+// A MySql informer holding objects resyncs them every resync period. One
+// that has gone this many periods, plus resyncGrace, without delivering an
+// event has lost its watch.
+const (
+	missedResyncs = 2
+	resyncGrace   = time.Minute
+)
+
+// healthChecker tracks the liveness of the informers and the workers so it
+// can be reported over HTTP.
+type healthChecker struct {
+	lock           sync.Mutex
+	standby        bool
+	watches        []*namespaceWatch
+	stopped        bool
+	workersStarted bool
+	busySince      map[int]time.Time
+}
+
+// namespaceWatch is the cache of one watched namespace as seen by the health
+// checks.
+type namespaceWatch struct {
+	namespace string
+	cache     *namespaceCache
+	resync    time.Duration
+	// When the MySql informer last delivered an event, resyncs included.
+	lastEvent time.Time
+}
+
+// Whether every informer of the namespace has synced.
+func (w *namespaceWatch) synced() bool {
+	for _, synced := range w.cache.synced {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+func newHealthChecker() *healthChecker {
+	return &healthChecker{busySince: map[int]time.Time{}}
+}
+
+// setStandby marks this replica as waiting for the leader lease. A standby
+// replica is ready even though it is not watching anything.
+func (h *healthChecker) setStandby(standby bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.standby = standby
+}
+
+// watch follows the cache of namespace, whose MySqls resync every resync.
+// It has to be called before the informers start.
+func (h *healthChecker) watch(namespace string, n *namespaceCache, resync time.Duration) {
+	w := &namespaceWatch{namespace: namespace, cache: n, resync: resync, lastEvent: time.Now()}
+	seen := func() {
+		h.lock.Lock()
+		defer h.lock.Unlock()
+		w.lastEvent = time.Now()
+	}
+	n.mySqlInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { seen() },
+		UpdateFunc: func(interface{}, interface{}) { seen() },
+		DeleteFunc: func(interface{}) { seen() },
+	})
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.watches = append(h.watches, w)
+	h.stopped = false
+}
+
+// watchesStopped records that the informers were stopped. Their caches are
+// forgotten, starting again watches new ones.
+func (h *healthChecker) watchesStopped() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.watches = nil
+	h.stopped = true
+}
+
+func (h *healthChecker) setWorkersStarted() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.workersStarted = true
+}
+
+// workerBusy marks a worker as having picked up a key.
+func (h *healthChecker) workerBusy(id int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.busySince[id] = time.Now()
+}
+
+// workerIdle marks a worker as having finished with its key.
+func (h *healthChecker) workerIdle(id int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.busySince, id)
+}
+
+// live returns an error if the informers were stopped, a synced MySql
+// informer holding objects has missed its resyncs, or a worker is wedged.
+func (h *healthChecker) live() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.stopped {
+		return fmt.Errorf("mysql informers were stopped")
+	}
+	for _, w := range h.watches {
+		if w.resync <= 0 || !w.cache.mySqlInformer.HasSynced() || len(w.cache.mySqlInformer.GetStore().ListKeys()) == 0 {
+			continue
+		}
+		if silent := time.Since(w.lastEvent); silent > missedResyncs*w.resync+resyncGrace {
+			return fmt.Errorf("mysql informer for namespace %q has delivered no events for %v, resyncs are due every %v", w.namespace, silent.Round(time.Second), w.resync)
+		}
+	}
+	for id, since := range h.busySince {
+		if time.Since(since) > workerStuckThreshold {
+			return fmt.Errorf("worker %d has been busy for %v", id, time.Since(since))
+		}
+	}
+	return nil
+}
+
+// ready returns an error until the caches have synced and the workers are
+// running.
+func (h *healthChecker) ready() error {
+	if err := h.live(); err != nil {
+		return err
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.standby {
+		return nil
+	}
+	if len(h.watches) == 0 {
+		return fmt.Errorf("mysql informers have not started")
+	}
+	for _, w := range h.watches {
+		if !w.synced() {
+			return fmt.Errorf("caches of namespace %q have not synced", w.namespace)
+		}
+	}
+	if !h.workersStarted {
+		return fmt.Errorf("workers have not started")
+	}
+	return nil
+}
+
+func healthHandler(check func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := check(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	}
+}
+
+// serveHTTP exposes /metrics, /healthz and /readyz on addr. It only returns
+// if the listener fails.
+func serveHTTP(addr string, health *healthChecker) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/healthz", healthHandler(health.live))
+	mux.Handle("/readyz", healthHandler(health.ready))
+	return http.ListenAndServe(addr, mux)
+}
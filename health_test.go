@@ -0,0 +1,107 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	mysqlfake "github.com/tonya11en/mysql-operator/pkg/client/clientset/versioned/fake"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func checkHealth(t *testing.T, what string, err error, want string) {
+	t.Helper()
+	switch {
+	case want == "" && err != nil:
+		t.Errorf("got %s error %v, want none", what, err)
+	case want != "" && (err == nil || !strings.Contains(err.Error(), want)):
+		t.Errorf("got %s error %v, want one containing %q", what, err, want)
+	}
+}
+
+func TestHealthChecker(t *testing.T) {
+	resync := time.Minute
+	h := newHealthChecker()
+	checkHealth(t, "readiness", h.ready(), "have not started")
+
+	n := newNamespaceCache(kubefake.NewSimpleClientset(), mysqlfake.NewSimpleClientset(testMySql("db")), v1.NamespaceAll, resync)
+	h.watch(v1.NamespaceAll, n, resync)
+	checkHealth(t, "readiness", h.ready(), "have not synced")
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	started := time.Now()
+	n.start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, n.synced...) {
+		t.Fatal("caches did not sync")
+	}
+	// Handlers are called asynchronously, wait for the add of the MySql.
+	err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		h.lock.Lock()
+		defer h.lock.Unlock()
+		return h.watches[0].lastEvent.After(started), nil
+	})
+	if err != nil {
+		t.Fatal("the add of the MySql was not seen")
+	}
+	checkHealth(t, "readiness", h.ready(), "workers have not started")
+	h.setWorkersStarted()
+	checkHealth(t, "readiness", h.ready(), "")
+	checkHealth(t, "liveness", h.live(), "")
+
+	// The informer holds a MySql but its resyncs stopped arriving.
+	h.lock.Lock()
+	h.watches[0].lastEvent = time.Now().Add(-missedResyncs*resync - resyncGrace - time.Second)
+	h.lock.Unlock()
+	checkHealth(t, "liveness", h.live(), "has delivered no events")
+
+	h.workerBusy(0)
+	h.lock.Lock()
+	h.watches[0].lastEvent = time.Now()
+	h.busySince[0] = time.Now().Add(-workerStuckThreshold - time.Second)
+	h.lock.Unlock()
+	checkHealth(t, "liveness", h.live(), "worker 0 has been busy")
+	h.workerIdle(0)
+	checkHealth(t, "liveness", h.live(), "")
+
+	h.watchesStopped()
+	checkHealth(t, "liveness", h.live(), "were stopped")
+}
+
+// An informer with nothing to resync is not expected to deliver events.
+func TestHealthCheckerEmptyCache(t *testing.T) {
+	resync := time.Minute
+	h := newHealthChecker()
+	n := newNamespaceCache(kubefake.NewSimpleClientset(), mysqlfake.NewSimpleClientset(), v1.NamespaceAll, resync)
+	h.watch(v1.NamespaceAll, n, resync)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	n.start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, n.synced...) {
+		t.Fatal("caches did not sync")
+	}
+
+	h.lock.Lock()
+	h.watches[0].lastEvent = time.Now().Add(-time.Hour)
+	h.lock.Unlock()
+	checkHealth(t, "liveness", h.live(), "")
+}
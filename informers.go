@@ -0,0 +1,116 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"time"
+
+	mysqlversioned "github.com/tonya11en/mysql-operator/pkg/client/clientset/versioned"
+	mysqlinformers "github.com/tonya11en/mysql-operator/pkg/client/informers/externalversions"
+	mysqllisters "github.com/tonya11en/mysql-operator/pkg/client/listers/myproject/v1beta1"
+	"k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	networkinglisters "k8s.io/client-go/listers/networking/v1"
+	policylisters "k8s.io/client-go/listers/policy/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// namespaceCache holds the shared informers of one watched namespace. The
+// controller reads MySqls and the objects backing them from these caches
+// instead of asking the API server on every reconcile.
+type namespaceCache struct {
+	kubeInformers  informers.SharedInformerFactory
+	mySqlInformers mysqlinformers.SharedInformerFactory
+
+	mySqlInformer cache.SharedIndexInformer
+	// Informers of the objects backing MySqls, see ownedHandlers.
+	owned []cache.SharedIndexInformer
+
+	mySqls      mysqllisters.MySqlLister
+	services    corelisters.ServiceLister
+	pvcs        corelisters.PersistentVolumeClaimLister
+	deployments appslisters.DeploymentLister
+	pdbs        policylisters.PodDisruptionBudgetLister
+	policies    networkinglisters.NetworkPolicyLister
+
+	synced []cache.InformerSynced
+}
+
+// Only MySqls are resynced, a resync of their children would requeue the
+// same MySqls again. The children are limited to the objects the operator
+// manages.
+func newNamespaceCache(clientset kubernetes.Interface, mySqlClientset mysqlversioned.Interface, namespace string, resync time.Duration) *namespaceCache {
+	kubeInformers := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *meta_v1.ListOptions) {
+			options.LabelSelector = managedSelector
+		}))
+	mySqlInformers := mysqlinformers.NewSharedInformerFactoryWithOptions(mySqlClientset, resync, mysqlinformers.WithNamespace(namespace))
+
+	mySqlInformer := mySqlInformers.Myproject().V1beta1().MySqls()
+	services := kubeInformers.Core().V1().Services()
+	pvcs := kubeInformers.Core().V1().PersistentVolumeClaims()
+	deployments := kubeInformers.Apps().V1().Deployments()
+	pdbs := kubeInformers.Policy().V1().PodDisruptionBudgets()
+	policies := kubeInformers.Networking().V1().NetworkPolicies()
+	owned := []cache.SharedIndexInformer{
+		services.Informer(),
+		pvcs.Informer(),
+		deployments.Informer(),
+		pdbs.Informer(),
+		policies.Informer(),
+		kubeInformers.Apps().V1().StatefulSets().Informer(),
+		kubeInformers.Core().V1().Secrets().Informer(),
+		kubeInformers.Core().V1().ConfigMaps().Informer(),
+	}
+
+	synced := []cache.InformerSynced{mySqlInformer.Informer().HasSynced}
+	for _, informer := range owned {
+		synced = append(synced, informer.HasSynced)
+	}
+	return &namespaceCache{
+		kubeInformers:  kubeInformers,
+		mySqlInformers: mySqlInformers,
+		mySqlInformer:  mySqlInformer.Informer(),
+		owned:          owned,
+		mySqls:         mySqlInformer.Lister(),
+		services:       services.Lister(),
+		pvcs:           pvcs.Lister(),
+		deployments:    deployments.Lister(),
+		pdbs:           pdbs.Lister(),
+		policies:       policies.Lister(),
+		synced:         synced,
+	}
+}
+
+func (n *namespaceCache) start(stopCh <-chan struct{}) {
+	n.kubeInformers.Start(stopCh)
+	n.mySqlInformers.Start(stopCh)
+}
+
+// The cache holding objects of namespace. When every namespace is watched
+// there is a single cache.
+func (c *MySqlController) cacheFor(namespace string) *namespaceCache {
+	if n, ok := c.caches[namespace]; ok {
+		return n
+	}
+	return c.caches[v1.NamespaceAll]
+}
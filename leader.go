@@ -0,0 +1,128 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// Name of the Lease object the operator replicas compete for.
+const leaseName = "mysql-operator"
+
+var isLeader = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: metricsNamespace,
+	Name:      "leader",
+	Help:      "Whether this replica currently holds the leader lease (1) or not (0).",
+})
+
+func init() {
+	prometheus.MustRegister(isLeader)
+}
+
+// leaderElectionConfig holds the knobs for lease based leader election.
+type leaderElectionConfig struct {
+	Enabled       bool     `json:"enabled"`
+	Namespace     string   `json:"namespace"`
+	LeaseDuration duration `json:"leaseDuration"`
+	RenewDeadline duration `json:"renewDeadline"`
+	RetryPeriod   duration `json:"retryPeriod"`
+}
+
+// runLeaderElection blocks until ctx is cancelled, calling run once this
+// replica acquires the lease. The stop channel handed to run is closed when
+// leadership ends. The lease is released when ctx is cancelled so that another
+// replica can take over without waiting for it to expire.
+func runLeaderElection(ctx context.Context, clientset kubernetes.Interface, cfg leaderElectionConfig, run func(stopCh chan struct{})) error {
+	id, err := leaderIdentity()
+	if err != nil {
+		return err
+	}
+
+	lock, err := resourcelock.New(
+		resourcelock.LeasesResourceLock,
+		cfg.Namespace,
+		leaseName,
+		clientset.CoreV1(),
+		clientset.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: id},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create leader election lock. %+v", err)
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   cfg.LeaseDuration.Duration,
+		RenewDeadline:   cfg.RenewDeadline.Duration,
+		RetryPeriod:     cfg.RetryPeriod.Duration,
+		ReleaseOnCancel: true,
+		Name:            leaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				log.Info("acquired leader lease", zap.String("identity", id))
+				isLeader.Set(1)
+
+				stopCh := make(chan struct{})
+				run(stopCh)
+				<-leaderCtx.Done()
+				close(stopCh)
+			},
+			OnStoppedLeading: func() {
+				isLeader.Set(0)
+				if ctx.Err() != nil {
+					// Shutting down, the lease has been released.
+					return
+				}
+				// Losing the lease while still running means another replica may
+				// already be reconciling, so stop here and let the pod restart.
+				log.Fatal("lost leader lease, exiting")
+			},
+			OnNewLeader: func(identity string) {
+				if identity != id {
+					log.Info("observed new leader", zap.String("identity", identity))
+				}
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create leader elector. %+v", err)
+	}
+
+	elector.Run(ctx)
+	return nil
+}
+
+// The identity of this replica in the lease. POD_NAME is set through the
+// downward API in mysql-operator.yaml; the hostname is used otherwise.
+func leaderIdentity() (string, error) {
+	if name := os.Getenv("POD_NAME"); name != "" {
+		return name, nil
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine leader election identity. %+v", err)
+	}
+	return host, nil
+}
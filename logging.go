@@ -0,0 +1,103 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+// log is the operator wide logger. It is replaced by setupLogging once the
+// configuration has been read.
+var log = zap.NewNop()
+
+// logConfig selects the format and verbosity of the operator's logs.
+type logConfig struct {
+	// Either "json" or "console".
+	Format string `json:"format"`
+	// One of "debug", "info", "warn" or "error".
+	Level string `json:"level"`
+	// Klog style verbosity; any value above zero turns on debug logs.
+	Verbosity int `json:"v"`
+}
+
+func (c logConfig) level() (zapcore.Level, error) {
+	if c.Verbosity > 0 {
+		return zapcore.DebugLevel, nil
+	}
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		return level, err
+	}
+	return level, nil
+}
+
+func (c logConfig) validate() []error {
+	var errs []error
+	if c.Format != "json" && c.Format != "console" {
+		errs = append(errs, fmt.Errorf("log.format: must be \"json\" or \"console\", got %q", c.Format))
+	}
+	if _, err := c.level(); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %v", err))
+	}
+	if c.Verbosity < 0 {
+		errs = append(errs, fmt.Errorf("log.v: must not be negative, got %d", c.Verbosity))
+	}
+	return errs
+}
+
+// setupLogging builds the operator wide logger from the configuration.
+func setupLogging(c logConfig) error {
+	level, err := c.level()
+	if err != nil {
+		return err
+	}
+
+	zc := zap.NewProductionConfig()
+	if c.Format == "console" {
+		zc = zap.NewDevelopmentConfig()
+	}
+	zc.Level = zap.NewAtomicLevelAt(level)
+	zc.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	logger, err := zc.Build()
+	if err != nil {
+		return err
+	}
+	log = logger
+	zap.RedirectStdLog(log)
+	return nil
+}
+
+// reconcileLogger returns a logger carrying the identity of the instance being
+// reconciled and a fresh ID tying together every line of one reconcile.
+func reconcileLogger(namespace string, name string) *zap.Logger {
+	return log.With(
+		zap.String("namespace", namespace),
+		zap.String("name", name),
+		zap.String("reconcileID", string(uuid.NewUUID())),
+	)
+}
+
+// withInstance adds the fields only known once the instance has been fetched.
+func withInstance(logger *zap.Logger, s *mysql.MySql) *zap.Logger {
+	return logger.With(zap.Int64("generation", s.Generation))
+}
@@ -23,84 +23,206 @@ which have the same license.
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
-	opkit "github.com/rook/operator-kit"
-	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1alpha1"
-	mysqlclient "github.com/tonya11en/mysql-operator/pkg/client/clientset/versioned/typed/myproject/v1alpha1"
+	mysqlversioned "github.com/tonya11en/mysql-operator/pkg/client/clientset/versioned"
+	"go.uber.org/zap"
 	"k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var (
+	kubeconfig  = flag.String("kubeconfig", "", "path to a kubeconfig file, for running outside the cluster (KUBECONFIG is also honored)")
+	kubeContext = flag.String("context", "", "kubeconfig context to use instead of the current one")
+	master      = flag.String("master", "", "address of the Kubernetes API server, overriding the one in the kubeconfig")
 )
 
 func main() {
-	fmt.Println("Getting kubernetes context")
-	context, mySqlClientset, err := createContext()
+	cfg, err := loadConfig(flag.CommandLine, os.Args[1:])
 	if err != nil {
-		fmt.Printf("failed to create context. %+v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "failed to load configuration. %+v\n", err)
+		os.Exit(2)
+	}
+	if err := setupLogging(cfg.Log); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to set up logging. %+v\n", err)
+		os.Exit(2)
 	}
+	defer log.Sync()
+
+	// Serve metrics and health checks for the lifetime of the process.
+	health := newHealthChecker()
+	go func() {
+		if err := serveHTTP(cfg.HTTPAddr, health); err != nil {
+			log.Fatal("failed to serve http", zap.String("addr", cfg.HTTPAddr), zap.Error(err))
+		}
+	}()
 
-	// Create and wait for CRD resources.
-	fmt.Println("Registering the mysql resource")
-	resources := []opkit.CustomResource{mysql.MySqlResource}
-	err = opkit.CreateCustomResources(*context, resources)
+	log.Info("getting kubernetes context")
+	cluster, mySqlClientset, dynamicClient, err := createContext(cfg)
 	if err != nil {
-		fmt.Printf("failed to create custom resource. %+v\n", err)
-		os.Exit(1)
+		log.Fatal("failed to create context", zap.Error(err))
 	}
 
-	// Create signals to stop watching the resources.
-	signalChan := make(chan os.Signal, 1)
-	stopChan := make(chan struct{})
-	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	// Every replica serves the webhooks, leader or not. They are started
+	// first so the CRD can point at the conversion webhook.
+	var conversion *apiextensionsv1.CustomResourceConversion
+	var webhooks *webhookServer
+	if cfg.Webhook.Enabled {
+		webhookConfig := cfg.Webhook
+		webhookConfig.ServiceNamespace = podNamespace(webhookConfig.ServiceNamespace)
+		webhooks = newWebhookServer(webhookConfig, cfg.Defaults, cluster.Clientset, mySqlClientset.MyprojectV1beta1())
+		if err := webhooks.start(); err != nil {
+			log.Fatal("failed to start admission webhooks", zap.Error(err))
+		}
+		conversion = webhooks.conversion()
+	}
+
+	// Create or update the CRD and wait for it to be served.
+	log.Info("registering the mysql resource")
+	if err := ensureCRD(cluster, conversion); err != nil {
+		log.Fatal("failed to create custom resource", zap.Error(err))
+	}
+
+	// Cancel everything once a shutdown signal arrives.
+	ctx, cancel := contextWithSignals()
+	defer cancel()
+
+	// Renew the webhook certificate before it expires. A new CA has to reach
+	// the CRD's conversion webhook as well.
+	if webhooks != nil {
+		go webhooks.rotateCerts(ctx.Done(), func() error {
+			return ensureCRD(cluster, webhooks.conversion())
+		})
+	}
+
+	cfg.NetworkPolicy.OperatorNamespace = podNamespace(cfg.NetworkPolicy.OperatorNamespace)
 
 	// Start watching the mysql resource.
-	controller := newMySqlController(context, mySqlClientset)
-	controller.StartWatch(v1.NamespaceAll, stopChan)
+	controller := newMySqlController(cluster.Clientset, mySqlClientset, dynamicClient, newEventRecorder(cluster.Clientset), health, cfg)
+	registerQueueDepth(controller.queue.Len)
+	run := func(stopChan chan struct{}) {
+		health.setStandby(false)
+		controller.StartWatch(cfg.Namespaces, stopChan)
+	}
+
+	if !cfg.LeaderElection.Enabled {
+		stopChan := make(chan struct{})
+		run(stopChan)
+		<-ctx.Done()
+		close(stopChan)
+		return
+	}
 
-	for {
+	health.setStandby(true)
+	electionConfig := cfg.LeaderElection
+	electionConfig.Namespace = podNamespace(electionConfig.Namespace)
+	err = runLeaderElection(ctx, cluster.Clientset, electionConfig, run)
+	if err != nil {
+		log.Fatal("failed to run leader election", zap.Error(err))
+	}
+}
+
+// Returns a context that is cancelled on SIGINT or SIGTERM.
+func contextWithSignals() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
 		select {
 		case <-signalChan:
-			fmt.Println("shutdown signal received, exiting...")
-			close(stopChan)
-			return
+			log.Info("shutdown signal received, exiting")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// Returns configured if it is set, otherwise the namespace the operator runs
+// in.
+func podNamespace(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+	return v1.NamespaceDefault
+}
+
+// Build a client config from the kubeconfig flags. With none of them set and
+// no KUBECONFIG in the environment the in-cluster config is used, falling back
+// to ~/.kube/config when the operator is not running in a pod.
+func buildConfig() (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = *kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: *kubeContext}
+	overrides.ClusterInfo.Server = *master
+
+	outOfCluster := *kubeconfig != "" || *kubeContext != "" || *master != "" || os.Getenv(clientcmd.RecommendedConfigPathEnvVar) != ""
+	if !outOfCluster {
+		config, err := rest.InClusterConfig()
+		if err == nil {
+			return config, nil
 		}
+		log.Info("not running in a cluster, falling back to kubeconfig", zap.Error(err))
 	}
+
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
 }
 
-func createContext() (*opkit.Context, mysqlclient.MyprojectV1alpha1Interface, error) {
-	config, err := rest.InClusterConfig()
+// clusterContext holds the clients the operator talks to the cluster with,
+// and how often and how long it waits for the CRD to be established.
+type clusterContext struct {
+	Clientset             kubernetes.Interface
+	APIExtensionClientset apiextensionsclient.Interface
+	Interval              time.Duration
+	Timeout               time.Duration
+}
+
+func createContext(cfg *operatorConfig) (*clusterContext, mysqlversioned.Interface, dynamic.Interface, error) {
+	config, err := buildConfig()
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get k8s config. %+v", err)
+		return nil, nil, nil, fmt.Errorf("failed to get k8s config. %+v", err)
 	}
 
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get k8s client. %+v", err)
+		return nil, nil, nil, fmt.Errorf("failed to get k8s client. %+v", err)
 	}
 
 	apiExtClientset, err := apiextensionsclient.NewForConfig(config)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create k8s API extension clientset. %+v", err)
+		return nil, nil, nil, fmt.Errorf("failed to create k8s API extension clientset. %+v", err)
+	}
+
+	mySqlClientset, err := mysqlversioned.NewForConfig(config)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create mysql clientset. %+v", err)
 	}
 
-	mySqlClientset, err := mysqlclient.NewForConfig(config)
+	dynamicClient, err := dynamic.NewForConfig(config)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create mysql clientset. %+v", err)
+		return nil, nil, nil, fmt.Errorf("failed to create dynamic client. %+v", err)
 	}
 
-	context := &opkit.Context{
+	cluster := &clusterContext{
 		Clientset:             clientset,
 		APIExtensionClientset: apiExtClientset,
-		Interval:              500 * time.Millisecond,
-		Timeout:               60 * time.Second,
+		Interval:              cfg.CRDPollInterval.Duration,
+		Timeout:               cfg.CRDTimeout.Duration,
 	}
-	return context, mySqlClientset, nil
+	return cluster, mySqlClientset, dynamicClient, nil
 
 }
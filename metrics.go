@@ -0,0 +1,159 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	"k8s.io/client-go/tools/metrics"
+)
+
+const metricsNamespace = "mysql_operator"
+
+var (
+	reconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconcile_total",
+		Help:      "Number of MySql reconciles, partitioned by result.",
+	}, []string{"result"})
+
+	reconcileDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconcile_duration_seconds",
+		Help:      "Time taken to reconcile a single MySql.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+	})
+
+	instancesByPhase = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "instances",
+		Help:      "Number of MySql instances managed by the operator, partitioned by phase.",
+	}, []string{"phase"})
+
+	driftCorrections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "drift_corrections_total",
+		Help:      "Number of times changed fields of an object backing a MySql were put back, partitioned by kind.",
+	}, []string{"kind"})
+
+	apiRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Latency of requests made to the Kubernetes API server, partitioned by verb and host.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+	}, []string{"verb", "host"})
+
+	apiRequestResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_requests_total",
+		Help:      "Number of requests made to the Kubernetes API server, partitioned by status code, method and host.",
+	}, []string{"code", "method", "host"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		reconcileTotal,
+		reconcileDuration,
+		instancesByPhase,
+		driftCorrections,
+		apiRequestDuration,
+		apiRequestResults,
+	)
+	metrics.Register(metrics.RegisterOpts{
+		RequestLatency: apiLatencyAdapter{},
+		RequestResult:  apiResultAdapter{},
+	})
+}
+
+// registerQueueDepth exposes the current length of the controller's work
+// queue. It is a function rather than a plain gauge so the value is read at
+// scrape time.
+func registerQueueDepth(length func() int) {
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "workqueue_depth",
+		Help:      "Number of MySql keys waiting to be reconciled.",
+	}, func() float64 {
+		return float64(length())
+	}))
+}
+
+// observeReconcile records the outcome of a single reconcile.
+func observeReconcile(start time.Time, err error) {
+	reconcileDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		reconcileTotal.WithLabelValues("error").Inc()
+		return
+	}
+	reconcileTotal.WithLabelValues("success").Inc()
+}
+
+// apiLatencyAdapter feeds client-go request latencies into prometheus.
+type apiLatencyAdapter struct{}
+
+func (apiLatencyAdapter) Observe(_ context.Context, verb string, u url.URL, latency time.Duration) {
+	apiRequestDuration.WithLabelValues(verb, u.Host).Observe(latency.Seconds())
+}
+
+// apiResultAdapter feeds client-go request results into prometheus.
+type apiResultAdapter struct{}
+
+func (apiResultAdapter) Increment(_ context.Context, code string, method string, host string) {
+	apiRequestResults.WithLabelValues(code, method, host).Inc()
+}
+
+// phaseTracker remembers the last known phase of every managed instance so
+// that the instances gauge can be kept accurate as objects come and go.
+type phaseTracker struct {
+	lock   sync.Mutex
+	phases map[string]mysql.MySqlPhase
+}
+
+func newPhaseTracker() *phaseTracker {
+	return &phaseTracker{phases: map[string]mysql.MySqlPhase{}}
+}
+
+// set records the phase for key. An empty phase forgets the instance.
+func (t *phaseTracker) set(key string, phase mysql.MySqlPhase) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if phase == "" {
+		delete(t.phases, key)
+	} else {
+		t.phases[key] = phase
+	}
+
+	counts := map[mysql.MySqlPhase]int{
+		mysql.MySqlPhasePending:  0,
+		mysql.MySqlPhaseRunning:  0,
+		mysql.MySqlPhaseFailed:   0,
+		mysql.MySqlPhaseDeleting: 0,
+		mysql.MySqlPhasePaused:   0,
+	}
+	for _, p := range t.phases {
+		counts[p]++
+	}
+	for p, n := range counts {
+		instancesByPhase.WithLabelValues(string(p)).Set(float64(n))
+	}
+}
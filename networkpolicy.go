@@ -0,0 +1,174 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	"go.uber.org/zap"
+	"k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// The label the API server puts on every namespace, holding its name. Clusters
+// before Kubernetes 1.21 do not set it.
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+// networkPolicyConfig picks out the operator's own pods, which the
+// NetworkPolicy of every instance lets in next to the sources it allows.
+type networkPolicyConfig struct {
+	// Label selectors in kubectl syntax, such as app=mysql-operator. An
+	// empty namespace selector matches OperatorNamespace by its name label.
+	OperatorSelector          string `json:"operatorSelector"`
+	OperatorNamespaceSelector string `json:"operatorNamespaceSelector"`
+	// The namespace the operator runs in, POD_NAMESPACE when left empty.
+	OperatorNamespace string `json:"operatorNamespace"`
+}
+
+func (c networkPolicyConfig) validate() []error {
+	var errs []error
+	if c.OperatorSelector == "" {
+		errs = append(errs, fmt.Errorf("networkPolicy.operatorSelector: must not be empty"))
+	} else if _, err := meta_v1.ParseToLabelSelector(c.OperatorSelector); err != nil {
+		errs = append(errs, fmt.Errorf("networkPolicy.operatorSelector: %v", err))
+	}
+	if _, err := meta_v1.ParseToLabelSelector(c.OperatorNamespaceSelector); err != nil {
+		errs = append(errs, fmt.Errorf("networkPolicy.operatorNamespaceSelector: %v", err))
+	}
+	if c.OperatorNamespace != "" {
+		for _, msg := range validation.IsDNS1123Label(c.OperatorNamespace) {
+			errs = append(errs, fmt.Errorf("networkPolicy.operatorNamespace: %q is not a valid namespace: %s", c.OperatorNamespace, msg))
+		}
+	}
+	return errs
+}
+
+// The peer matching the operator's pods. The selectors are checked when the
+// config is loaded. Without a namespace selector only the operator's own
+// namespace is matched; with no namespace known either, a peer without a
+// namespace selector matches the MySql's namespace.
+func (c networkPolicyConfig) operatorPeer() networkingv1.NetworkPolicyPeer {
+	pods, _ := meta_v1.ParseToLabelSelector(c.OperatorSelector)
+	peer := networkingv1.NetworkPolicyPeer{PodSelector: pods}
+	switch {
+	case c.OperatorNamespaceSelector != "":
+		peer.NamespaceSelector, _ = meta_v1.ParseToLabelSelector(c.OperatorNamespaceSelector)
+	case c.OperatorNamespace != "":
+		peer.NamespaceSelector = &meta_v1.LabelSelector{
+			MatchLabels: map[string]string{namespaceNameLabel: c.OperatorNamespace},
+		}
+	}
+	return peer
+}
+
+// The NetworkPolicy of a MySql. MySQL only accepts connections from the
+// allowed sources and the operator, the ports of sidecars from the sources
+// allowed to reach sidecars and the operator. Sidecars reach MySQL over
+// localhost, which NetworkPolicies do not apply to.
+func newNetworkPolicy(s *mysql.MySql, operator networkingv1.NetworkPolicyPeer, podSpec *v1.PodSpec, labels map[string]string) *networkingv1.NetworkPolicy {
+	tcp := v1.ProtocolTCP
+	mysqlPort := intstr.FromInt(3306)
+	spec := s.Spec.NetworkPolicy
+	ingress := []networkingv1.NetworkPolicyIngressRule{{
+		Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &mysqlPort}},
+		From:  policyPeers(operator, spec.AllowFrom),
+	}}
+	if ports := sidecarPorts(podSpec); len(ports) > 0 {
+		sidecarPeers := spec.SidecarAllowFrom
+		if len(sidecarPeers) == 0 {
+			sidecarPeers = spec.AllowFrom
+		}
+		ingress = append(ingress, networkingv1.NetworkPolicyIngressRule{Ports: ports, From: policyPeers(operator, sidecarPeers)})
+	}
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      s.Name,
+			Namespace: s.Namespace,
+			Labels:    labels,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: meta_v1.LabelSelector{
+				MatchLabels: managedLabels(s, nil),
+			},
+			Ingress:     ingress,
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+}
+
+// The operator followed by the peers of a MySql, as NetworkPolicy peers.
+func policyPeers(operator networkingv1.NetworkPolicyPeer, peers []mysql.NetworkPolicyPeer) []networkingv1.NetworkPolicyPeer {
+	from := []networkingv1.NetworkPolicyPeer{operator}
+	for _, peer := range peers {
+		from = append(from, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: peer.NamespaceSelector.DeepCopy(),
+			PodSelector:       peer.PodSelector.DeepCopy(),
+		})
+	}
+	return from
+}
+
+// The ports declared by every container of the pod but MySQL's.
+func sidecarPorts(podSpec *v1.PodSpec) []networkingv1.NetworkPolicyPort {
+	var ports []networkingv1.NetworkPolicyPort
+	for _, c := range podSpec.Containers {
+		if c.Name == mysqlContainerName {
+			continue
+		}
+		for _, p := range c.Ports {
+			protocol := p.Protocol
+			if protocol == "" {
+				protocol = v1.ProtocolTCP
+			}
+			port := intstr.FromInt(int(p.ContainerPort))
+			ports = append(ports, networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &port})
+		}
+	}
+	return ports
+}
+
+// Make a network policy.
+func (c *MySqlController) makeNetworkPolicy(log *zap.Logger, policy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error) {
+	log.Debug("making network policy")
+	created, err := c.clientset.NetworkingV1().NetworkPolicies(policy.Namespace).Create(context.TODO(), policy, meta_v1.CreateOptions{})
+
+	logCreate(log, "networkpolicy", policy.Name, err)
+
+	return created, err
+}
+
+// Delete the network policy of a MySql whose spec no longer asks for one, so
+// anyone may connect again.
+func (c *MySqlController) removeNetworkPolicy(log *zap.Logger, s *mysql.MySql, name string) error {
+	err := c.clientset.NetworkingV1().NetworkPolicies(s.Namespace).Delete(context.TODO(), name, meta_v1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete network policy. %+v", err)
+	}
+	log.Info("deleted networkpolicy", zap.String("networkpolicy", name))
+	c.recorder.Eventf(s, v1.EventTypeNormal, reasonDeleted, "Deleted NetworkPolicy %s, spec.networkPolicy.allowFrom is empty", name)
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/tonya11en/mysql-operator/pkg/apis/myproject"
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+var managedSelector = labels.SelectorFromSet(labels.Set{myproject.ManagedByLabel: myproject.ManagedByValue}).String()
+
+// The labels marking an object as backing s, on top of base.
+func managedLabels(s *mysql.MySql, base map[string]string) map[string]string {
+	l := map[string]string{}
+	for k, v := range base {
+		l[k] = v
+	}
+	l[myproject.ManagedByLabel] = myproject.ManagedByValue
+	l[myproject.InstanceLabel] = s.Name
+	return l
+}
+
+// A merge patch adding the managed labels to an object created before the
+// operator labelled what it creates, so the informers pick it up.
+func managedLabelsPatch(s *mysql.MySql) []byte {
+	patch, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": managedLabels(s, nil),
+		},
+	})
+	return patch
+}
+
+// Handlers for the informers of the objects backing MySqls. A change to one
+// of them requeues its MySql, so a deleted Service or a Deployment scaled to
+// zero is noticed straight away.
+func (c *MySqlController) ownedHandlers() cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueOwner,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldMeta, err1 := meta.Accessor(oldObj)
+			newMeta, err2 := meta.Accessor(newObj)
+			if err1 == nil && err2 == nil && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+				return
+			}
+			c.enqueueOwner(newObj)
+		},
+		DeleteFunc: c.enqueueOwner,
+	}
+}
+
+// Queue the key of the MySql owning obj, if any.
+func (c *MySqlController) enqueueOwner(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	object, err := meta.Accessor(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	name := object.GetLabels()[myproject.InstanceLabel]
+	if name == "" {
+		return
+	}
+	log.Debug("handling change to owned object", zap.String("namespace", object.GetNamespace()), zap.String("object", object.GetName()), zap.String("owner", name))
+	c.queue.Add(object.GetNamespace() + "/" + name)
+}
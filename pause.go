@@ -0,0 +1,84 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	"go.uber.org/zap"
+	"k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func findCondition(conditions []mysql.MySqlCondition, conditionType mysql.MySqlConditionType) *mysql.MySqlCondition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// Add or replace the condition of the same type, keeping its transition time
+// when the status stays the same.
+func setCondition(conditions []mysql.MySqlCondition, condition mysql.MySqlCondition) []mysql.MySqlCondition {
+	existing := findCondition(conditions, condition.Type)
+	if existing == nil {
+		return append(conditions, condition)
+	}
+	if existing.Status == condition.Status {
+		condition.LastTransitionTime = existing.LastTransitionTime
+	}
+	*existing = condition
+	return conditions
+}
+
+func isPaused(status mysql.MySqlStatus) bool {
+	condition := findCondition(status.Conditions, mysql.MySqlConditionPaused)
+	return condition != nil && condition.Status == v1.ConditionTrue
+}
+
+// Record that a paused MySql is being left alone. Nothing else is changed
+// until spec.paused is cleared or the MySql is deleted.
+func (c *MySqlController) pause(log *zap.Logger, key string, s *mysql.MySql) error {
+	log.Debug("mysql is paused")
+	message := "spec.paused is set, the operator makes no changes"
+	status := *s.Status.DeepCopy()
+	status.Phase = mysql.MySqlPhasePaused
+	status.Message = message
+	status.Conditions = setCondition(status.Conditions, mysql.MySqlCondition{
+		Type:               mysql.MySqlConditionPaused,
+		Status:             v1.ConditionTrue,
+		LastTransitionTime: meta_v1.Now(),
+		Reason:             reasonPaused,
+		Message:            message,
+	})
+	return c.setStatus(log, key, s, status)
+}
+
+// Flip the Paused condition of a MySql that is no longer paused.
+func resumeConditions(conditions []mysql.MySqlCondition) []mysql.MySqlCondition {
+	if condition := findCondition(conditions, mysql.MySqlConditionPaused); condition == nil || condition.Status != v1.ConditionTrue {
+		return conditions
+	}
+	return setCondition(conditions, mysql.MySqlCondition{
+		Type:               mysql.MySqlConditionPaused,
+		Status:             v1.ConditionFalse,
+		LastTransitionTime: meta_v1.Now(),
+		Reason:             reasonResumed,
+		Message:            "spec.paused was cleared",
+	})
+}
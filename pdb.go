@@ -0,0 +1,64 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	"go.uber.org/zap"
+	policyv1 "k8s.io/api/policy/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// The PodDisruptionBudget of a MySql. One pod at a time may be evicted from
+// an instance with several, an instance with one follows its spec. The MySql
+// owns the budget, so the garbage collector removes it should the operator
+// miss the deletion.
+func newPDB(s *mysql.MySql, replicas int32, labels map[string]string) *policyv1.PodDisruptionBudget {
+	maxUnavailable := intstr.FromInt(1)
+	if replicas <= 1 && s.Spec.DisruptionBudget.MaxUnavailable != nil {
+		maxUnavailable = intstr.FromInt(int(*s.Spec.DisruptionBudget.MaxUnavailable))
+	}
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      s.Name,
+			Namespace: s.Namespace,
+			Labels:    labels,
+			OwnerReferences: []meta_v1.OwnerReference{
+				*meta_v1.NewControllerRef(s, mysql.SchemeGroupVersion.WithKind(mysql.MySqlKind)),
+			},
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &maxUnavailable,
+			Selector: &meta_v1.LabelSelector{
+				MatchLabels: managedLabels(s, nil),
+			},
+		},
+	}
+}
+
+// Make a pod disruption budget.
+func (c *MySqlController) makePDB(log *zap.Logger, pdb *policyv1.PodDisruptionBudget) (*policyv1.PodDisruptionBudget, error) {
+	log.Debug("making pod disruption budget")
+	created, err := c.clientset.PolicyV1().PodDisruptionBudgets(pdb.Namespace).Create(context.TODO(), pdb, meta_v1.CreateOptions{})
+
+	logCreate(log, "poddisruptionbudget", pdb.Name, err)
+
+	return created, err
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package myproject holds the names the operator gives the objects backing a
+// MySql, shared with the kubectl plugin so the two cannot drift apart.
+package myproject
+
+// Labels put on every object backing a MySql. The operator only watches
+// objects carrying them, and maps a change back to the MySql named by
+// InstanceLabel.
+const (
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	ManagedByValue = "mysql-operator"
+	InstanceLabel  = "app.kubernetes.io/instance"
+)
+
+// Suffixes appended to the name of a MySql to name its claim and the Secret
+// its CA certificate is published in, and the key of the certificate there.
+const (
+	PVCNameSuffix  = "-pv-claim"
+	CASecretSuffix = "-ca"
+	CACertKey      = "ca.crt"
+)
@@ -1,5 +1,5 @@
 // +k8s:deepcopy-gen=package,register
 
 // Package v1 is the v1 version of the API.
-// +groupName=myproject
+// +groupName=myproject.io
 package v1alpha1
@@ -1,10 +1,6 @@
 package v1alpha1
 
 import (
-	"reflect"
-
-	opkit "github.com/rook/operator-kit"
-	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -19,14 +15,11 @@ var (
 // schemeGroupVersion is group version used to register these objects
 var SchemeGroupVersion = schema.GroupVersion{Group: "myproject.io", Version: "v1alpha1"}
 
-var MySqlResource = opkit.CustomResource{
-	Name:    "mysql",
-	Plural:  "mysqls",
-	Group:   "myproject.io",
-	Version: "v1alpha1",
-	Scope:   apiextensionsv1beta1.NamespaceScoped,
-	Kind:    reflect.TypeOf(MySql{}).Name(),
-}
+// Names the MySql resource is served under.
+const (
+	MySqlPlural = "mysqls"
+	MySqlKind   = "MySql"
+)
 
 func init() {
 	localSchemeBuilder.Register(addKnownTypes)
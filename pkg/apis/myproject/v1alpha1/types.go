@@ -1,20 +1,135 @@
 package v1alpha1
 
-import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 // +genclient
 // +genclient:noStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:path=mysqls,singular=mysql,scope=Namespaced
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.image`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
+// MySql is a MySQL server managed by the operator.
 type MySql struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
-	Spec              MySqlSpec `json:"spec"`
+	Spec              MySqlSpec   `json:"spec"`
+	Status            MySqlStatus `json:"status,omitempty"`
 }
 
+// MySqlSpec is the desired state of a MySql.
 type MySqlSpec struct {
-	Image        string `json:"image"`
+	// Container image to run. Defaults to the operator's configured image.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^[^\s]+$`
+	Image string `json:"image,omitempty"`
+	// Password of the MySQL root user. Empty when the object was created
+	// through v1beta1 with a Secret reference instead.
+	// +optional
 	RootPassword string `json:"rootPassword"`
+	// The volume holding the instance's data.
+	// +optional
+	Storage StorageSpec `json:"storage,omitempty"`
+	// What happens to the instance's volume when the MySql is deleted.
+	// Defaults to Delete.
+	// +optional
+	// +kubebuilder:default=Delete
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// Refuse to delete the MySql or its volume until this is cleared.
+	// +optional
+	// +kubebuilder:default=false
+	DeletionProtection bool `json:"deletionProtection,omitempty"`
+}
+
+// StorageSpec describes the PersistentVolumeClaim backing an instance.
+type StorageSpec struct {
+	// Requested size of the volume. Defaults to the operator's configured
+	// size. It can be grown, if the storage class allows expansion, but not
+	// shrunk.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+	// Storage class of the volume. Defaults to the cluster's default class.
+	// Cannot be changed once the instance is created.
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+	// Zone the volume and the pod are placed in. Cannot be changed once the
+	// instance is created.
+	// +optional
+	Zone string `json:"zone,omitempty"`
+}
+
+// DeletionPolicy decides what happens to the PersistentVolumeClaim of an
+// instance when the MySql is deleted.
+// +kubebuilder:validation:Enum=Delete;Retain;Snapshot
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the claim along with everything else.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain leaves the claim behind, labelled so that a new
+	// MySql with the same name adopts it.
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicySnapshot takes a VolumeSnapshot of the claim and deletes
+	// the claim once the snapshot is ready to use.
+	DeletionPolicySnapshot DeletionPolicy = "Snapshot"
+)
+
+// MySqlPhase is a short, machine readable summary of where an instance is in
+// its lifecycle.
+// +kubebuilder:validation:Enum=Pending;Running;Failed;Deleting;Paused
+type MySqlPhase string
+
+const (
+	// MySqlPhasePending means the operator has seen the instance but has not
+	// finished creating its resources.
+	MySqlPhasePending MySqlPhase = "Pending"
+	// MySqlPhaseRunning means every resource backing the instance was created.
+	MySqlPhaseRunning MySqlPhase = "Running"
+	// MySqlPhaseFailed means the last attempt to reconcile the instance failed.
+	MySqlPhaseFailed MySqlPhase = "Failed"
+	// MySqlPhaseDeleting means the MySql was deleted and the operator is
+	// carrying out its deletion policy.
+	MySqlPhaseDeleting MySqlPhase = "Deleting"
+	// MySqlPhasePaused means the operator leaves the instance alone until
+	// spec.paused is cleared.
+	MySqlPhasePaused MySqlPhase = "Paused"
+)
+
+// MySqlConditionType is the kind of a MySqlCondition.
+type MySqlConditionType string
+
+const (
+	// MySqlConditionPaused is true while the operator makes no changes to
+	// the instance.
+	MySqlConditionPaused MySqlConditionType = "Paused"
+)
+
+// MySqlCondition is an observation about one aspect of a MySql.
+type MySqlCondition struct {
+	Type   MySqlConditionType     `json:"type"`
+	Status corev1.ConditionStatus `json:"status"`
+	// When the status last changed.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Machine readable reason for the last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// MySqlStatus is the state of a MySql as last observed by the operator.
+type MySqlStatus struct {
+	Phase           MySqlPhase `json:"phase,omitempty"`
+	Message         string     `json:"message,omitempty"`
+	ExternalAddress string     `json:"externalAddress,omitempty"`
+	// +optional
+	Conditions []MySqlCondition `json:"conditions,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -22,5 +137,5 @@ type MySqlSpec struct {
 type MySqlList struct {
 	metav1.TypeMeta `json:",inline"`
 	metav1.ListMeta `json:"metadata"`
-	Items           []MySql `json:items"`
+	Items           []MySql `json:"items"`
 }
@@ -0,0 +1,185 @@
+package v1beta1
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConversionDataAnnotation holds the fields of a v1beta1 MySql that v1alpha1
+// has no place for, so that converting to v1alpha1 and back loses nothing.
+const ConversionDataAnnotation = "myproject.io/conversion-data"
+
+// conversionData is the content of ConversionDataAnnotation.
+type conversionData struct {
+	Version               string                       `json:"version,omitempty"`
+	Resources             *corev1.ResourceRequirements `json:"resources,omitempty"`
+	Scheduling            *SchedulingSpec              `json:"scheduling,omitempty"`
+	Security              *SecuritySpec                `json:"security,omitempty"`
+	PodTemplate           *PodTemplateSpec             `json:"podTemplate,omitempty"`
+	MaxUnavailable        *int32                       `json:"maxUnavailable,omitempty"`
+	NetworkPolicy         *NetworkPolicySpec           `json:"networkPolicy,omitempty"`
+	RootPasswordSecretRef *corev1.SecretKeySelector    `json:"rootPasswordSecretRef,omitempty"`
+	Service               *ServiceSpec                 `json:"service,omitempty"`
+	TLS                   *TLSSpec                     `json:"tls,omitempty"`
+	Paused                bool                         `json:"paused,omitempty"`
+}
+
+// ConvertFromV1alpha1 converts a v1alpha1 MySql to v1beta1, restoring any
+// fields saved by ConvertToV1alpha1.
+func ConvertFromV1alpha1(in *v1alpha1.MySql, out *MySql) error {
+	out.TypeMeta = metav1.TypeMeta{APIVersion: SchemeGroupVersion.String(), Kind: "MySql"}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = MySqlSpec{
+		Image: in.Spec.Image,
+		Storage: StorageSpec{
+			StorageClassName: in.Spec.Storage.StorageClassName,
+			Zone:             in.Spec.Storage.Zone,
+		},
+		Credentials: CredentialsSpec{
+			RootPassword: in.Spec.RootPassword,
+		},
+		DeletionPolicy:     DeletionPolicy(in.Spec.DeletionPolicy),
+		DeletionProtection: in.Spec.DeletionProtection,
+	}
+	if in.Spec.Storage.Size != nil {
+		size := in.Spec.Storage.Size.DeepCopy()
+		out.Spec.Storage.Size = &size
+	}
+	out.Status = MySqlStatus{
+		Phase:           MySqlPhase(in.Status.Phase),
+		Message:         in.Status.Message,
+		ExternalAddress: in.Status.ExternalAddress,
+	}
+	for _, c := range in.Status.Conditions {
+		out.Status.Conditions = append(out.Status.Conditions, MySqlCondition{
+			Type:               MySqlConditionType(c.Type),
+			Status:             c.Status,
+			LastTransitionTime: *c.LastTransitionTime.DeepCopy(),
+			Reason:             c.Reason,
+			Message:            c.Message,
+		})
+	}
+
+	raw, ok := out.Annotations[ConversionDataAnnotation]
+	if !ok {
+		return nil
+	}
+	delete(out.Annotations, ConversionDataAnnotation)
+	if len(out.Annotations) == 0 {
+		out.Annotations = nil
+	}
+	var data conversionData
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		return fmt.Errorf("failed to decode the %s annotation. %+v", ConversionDataAnnotation, err)
+	}
+	out.Spec.Version = data.Version
+	if data.Resources != nil {
+		out.Spec.Resources = *data.Resources
+	}
+	if data.Scheduling != nil {
+		out.Spec.Scheduling = *data.Scheduling
+	}
+	if data.Security != nil {
+		out.Spec.Security = *data.Security
+	}
+	if data.PodTemplate != nil {
+		out.Spec.PodTemplate = *data.PodTemplate
+	}
+	out.Spec.DisruptionBudget.MaxUnavailable = data.MaxUnavailable
+	if data.NetworkPolicy != nil {
+		out.Spec.NetworkPolicy = *data.NetworkPolicy
+	}
+	out.Spec.Credentials.RootPasswordSecretRef = data.RootPasswordSecretRef
+	if data.Service != nil {
+		out.Spec.Service = *data.Service
+	}
+	if data.TLS != nil {
+		out.Spec.TLS = *data.TLS
+	}
+	out.Spec.Paused = data.Paused
+	return nil
+}
+
+// ConvertToV1alpha1 converts a v1beta1 MySql to v1alpha1. Fields v1alpha1
+// cannot hold are saved in ConversionDataAnnotation.
+func ConvertToV1alpha1(in *MySql, out *v1alpha1.MySql) error {
+	out.TypeMeta = metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "MySql"}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = v1alpha1.MySqlSpec{
+		Image:        in.Spec.Image,
+		RootPassword: in.Spec.Credentials.RootPassword,
+		Storage: v1alpha1.StorageSpec{
+			StorageClassName: in.Spec.Storage.StorageClassName,
+			Zone:             in.Spec.Storage.Zone,
+		},
+		DeletionPolicy:     v1alpha1.DeletionPolicy(in.Spec.DeletionPolicy),
+		DeletionProtection: in.Spec.DeletionProtection,
+	}
+	if in.Spec.Storage.Size != nil {
+		size := in.Spec.Storage.Size.DeepCopy()
+		out.Spec.Storage.Size = &size
+	}
+	out.Status = v1alpha1.MySqlStatus{
+		Phase:           v1alpha1.MySqlPhase(in.Status.Phase),
+		Message:         in.Status.Message,
+		ExternalAddress: in.Status.ExternalAddress,
+	}
+	for _, c := range in.Status.Conditions {
+		out.Status.Conditions = append(out.Status.Conditions, v1alpha1.MySqlCondition{
+			Type:               v1alpha1.MySqlConditionType(c.Type),
+			Status:             c.Status,
+			LastTransitionTime: *c.LastTransitionTime.DeepCopy(),
+			Reason:             c.Reason,
+			Message:            c.Message,
+		})
+	}
+
+	delete(out.Annotations, ConversionDataAnnotation)
+	data := conversionData{
+		Version:               in.Spec.Version,
+		RootPasswordSecretRef: in.Spec.Credentials.RootPasswordSecretRef,
+		Paused:                in.Spec.Paused,
+		MaxUnavailable:        in.Spec.DisruptionBudget.MaxUnavailable,
+	}
+	if len(in.Spec.Resources.Limits) > 0 || len(in.Spec.Resources.Requests) > 0 {
+		data.Resources = in.Spec.Resources.DeepCopy()
+	}
+	if !reflect.DeepEqual(in.Spec.Scheduling, SchedulingSpec{}) {
+		data.Scheduling = in.Spec.Scheduling.DeepCopy()
+	}
+	if !reflect.DeepEqual(in.Spec.Security, SecuritySpec{}) {
+		data.Security = in.Spec.Security.DeepCopy()
+	}
+	if !reflect.DeepEqual(in.Spec.PodTemplate, PodTemplateSpec{}) {
+		data.PodTemplate = in.Spec.PodTemplate.DeepCopy()
+	}
+	if !reflect.DeepEqual(in.Spec.NetworkPolicy, NetworkPolicySpec{}) {
+		data.NetworkPolicy = in.Spec.NetworkPolicy.DeepCopy()
+	}
+	if !reflect.DeepEqual(in.Spec.Service, ServiceSpec{}) {
+		data.Service = in.Spec.Service.DeepCopy()
+	}
+	if in.Spec.TLS != (TLSSpec{}) {
+		data.TLS = in.Spec.TLS.DeepCopy()
+	}
+	if data == (conversionData{}) {
+		if len(out.Annotations) == 0 {
+			out.Annotations = nil
+		}
+		return nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode the %s annotation. %+v", ConversionDataAnnotation, err)
+	}
+	if out.Annotations == nil {
+		out.Annotations = map[string]string{}
+	}
+	out.Annotations[ConversionDataAnnotation] = string(raw)
+	return nil
+}
@@ -0,0 +1,246 @@
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/diff"
+)
+
+func quantity(s string) *resource.Quantity {
+	q := resource.MustParse(s)
+	return &q
+}
+
+func TestRoundTripFromV1beta1(t *testing.T) {
+	runtimeClass := "gvisor"
+	fsGroup := int64(2000)
+	readOnly := false
+	maxUnavailable := int32(0)
+	tests := []struct {
+		name string
+		in   *MySql
+	}{
+		{
+			name: "minimal",
+			in: &MySql{
+				ObjectMeta: metav1.ObjectMeta{Name: "mysql", Namespace: "default"},
+				Spec: MySqlSpec{
+					Credentials: CredentialsSpec{RootPassword: "password"},
+				},
+			},
+		},
+		{
+			name: "every field",
+			in: &MySql{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "mysql",
+					Namespace:   "default",
+					Annotations: map[string]string{"team": "storage"},
+					Finalizers:  []string{"myproject.io/mysql-cleanup"},
+				},
+				Spec: MySqlSpec{
+					Version: "8.0",
+					Image:   "registry.example.com/mysql:8.0",
+					Storage: StorageSpec{
+						Size:             quantity("20Gi"),
+						StorageClassName: "fast",
+						Zone:             "us-east1-b",
+					},
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+						Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+					},
+					Scheduling: SchedulingSpec{
+						NodeSelector: map[string]string{"pool": "databases"},
+						Tolerations: []corev1.Toleration{{
+							Key:      "dedicated",
+							Operator: corev1.TolerationOpEqual,
+							Value:    "mysql",
+							Effect:   corev1.TaintEffectNoSchedule,
+						}},
+						Affinity: &corev1.Affinity{
+							PodAntiAffinity: &corev1.PodAntiAffinity{
+								RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+									LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "mysql"}},
+									TopologyKey:   corev1.LabelHostname,
+								}},
+							},
+						},
+						TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{
+							MaxSkew:           1,
+							TopologyKey:       corev1.LabelZoneFailureDomain,
+							WhenUnsatisfiable: corev1.ScheduleAnyway,
+						}},
+						PriorityClassName: "databases",
+						RuntimeClassName:  &runtimeClass,
+					},
+					Security: SecuritySpec{
+						PodSecurityContext:       &corev1.PodSecurityContext{FSGroup: &fsGroup},
+						ContainerSecurityContext: &corev1.SecurityContext{ReadOnlyRootFilesystem: &readOnly},
+						SeccompProfile:           "localhost/mysql.json",
+					},
+					PodTemplate: PodTemplateSpec{
+						Containers: []corev1.Container{{
+							Name:         "log-shipper",
+							Image:        "fluent/fluent-bit:1.3",
+							VolumeMounts: []corev1.VolumeMount{{Name: "logs", MountPath: "/logs"}},
+						}},
+						InitContainers: []corev1.Container{{Name: "init-config", Image: "busybox"}},
+						Volumes: []corev1.Volume{{
+							Name:         "logs",
+							VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+						}},
+						VolumeMounts: []corev1.VolumeMount{{Name: "logs", MountPath: "/var/log/mysql"}},
+						Env:          []corev1.EnvVar{{Name: "TZ", Value: "UTC"}},
+						Override:     &runtime.RawExtension{Raw: []byte(`{"spec":{"terminationGracePeriodSeconds":60}}`)},
+					},
+					Credentials: CredentialsSpec{
+						RootPasswordSecretRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "mysql-root"},
+							Key:                  "password",
+						},
+					},
+					Service: ServiceSpec{
+						Type:                     corev1.ServiceTypeLoadBalancer,
+						Ports:                    []ServicePort{{Name: "mysql", Port: 3306, NodePort: 30306}},
+						Annotations:              map[string]string{"service.beta.kubernetes.io/aws-load-balancer-internal": "true"},
+						LoadBalancerSourceRanges: []string{"10.0.0.0/8"},
+						ExternalTrafficPolicy:    corev1.ServiceExternalTrafficPolicyTypeLocal,
+					},
+					TLS: TLSSpec{
+						Enabled:                true,
+						SecretName:             "mysql-certs",
+						RequireSecureTransport: true,
+					},
+					DisruptionBudget: DisruptionBudgetSpec{MaxUnavailable: &maxUnavailable},
+					NetworkPolicy: NetworkPolicySpec{
+						AllowFrom: []NetworkPolicyPeer{{
+							NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "web"}},
+							PodSelector: &metav1.LabelSelector{
+								MatchExpressions: []metav1.LabelSelectorRequirement{{
+									Key:      "app",
+									Operator: metav1.LabelSelectorOpIn,
+									Values:   []string{"shop", "admin"},
+								}},
+							},
+						}},
+						SidecarAllowFrom: []NetworkPolicyPeer{{
+							NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "monitoring"}},
+						}},
+					},
+					Paused:             true,
+					DeletionPolicy:     DeletionPolicySnapshot,
+					DeletionProtection: true,
+				},
+				Status: MySqlStatus{
+					Phase:           MySqlPhasePaused,
+					ExternalAddress: "203.0.113.7",
+					Conditions: []MySqlCondition{{
+						Type:               MySqlConditionPaused,
+						Status:             corev1.ConditionTrue,
+						LastTransitionTime: metav1.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+						Reason:             "Paused",
+						Message:            "spec.paused is set",
+					}},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.in.TypeMeta = metav1.TypeMeta{APIVersion: SchemeGroupVersion.String(), Kind: "MySql"}
+			alpha := &v1alpha1.MySql{}
+			if err := ConvertToV1alpha1(test.in, alpha); err != nil {
+				t.Fatal(err)
+			}
+			out := &MySql{}
+			if err := ConvertFromV1alpha1(alpha, out); err != nil {
+				t.Fatal(err)
+			}
+			if !equality.Semantic.DeepEqual(test.in, out) {
+				t.Errorf("round trip changed the object: %s", diff.Diff(test.in, out))
+			}
+		})
+	}
+}
+
+func TestRoundTripFromV1alpha1(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *v1alpha1.MySql
+	}{
+		{
+			name: "minimal",
+			in: &v1alpha1.MySql{
+				ObjectMeta: metav1.ObjectMeta{Name: "mysql", Namespace: "default"},
+				Spec:       v1alpha1.MySqlSpec{RootPassword: "password"},
+			},
+		},
+		{
+			name: "every field",
+			in: &v1alpha1.MySql{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "mysql",
+					Namespace:   "default",
+					Annotations: map[string]string{"team": "storage"},
+				},
+				Spec: v1alpha1.MySqlSpec{
+					Image:        "mysql:5.6",
+					RootPassword: "password",
+					Storage: v1alpha1.StorageSpec{
+						Size:             quantity("20G"),
+						StorageClassName: "standard",
+						Zone:             "us-east1-c",
+					},
+					DeletionPolicy:     v1alpha1.DeletionPolicyRetain,
+					DeletionProtection: true,
+				},
+				Status: v1alpha1.MySqlStatus{
+					Phase:   v1alpha1.MySqlPhaseFailed,
+					Message: "boom",
+					Conditions: []v1alpha1.MySqlCondition{{
+						Type:               v1alpha1.MySqlConditionPaused,
+						Status:             corev1.ConditionFalse,
+						LastTransitionTime: metav1.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+						Reason:             "Resumed",
+					}},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.in.TypeMeta = metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "MySql"}
+			beta := &MySql{}
+			if err := ConvertFromV1alpha1(test.in, beta); err != nil {
+				t.Fatal(err)
+			}
+			out := &v1alpha1.MySql{}
+			if err := ConvertToV1alpha1(beta, out); err != nil {
+				t.Fatal(err)
+			}
+			if !equality.Semantic.DeepEqual(test.in, out) {
+				t.Errorf("round trip changed the object: %s", diff.Diff(test.in, out))
+			}
+		})
+	}
+}
+
+func TestConvertFromV1alpha1BadAnnotation(t *testing.T) {
+	in := &v1alpha1.MySql{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "mysql",
+			Annotations: map[string]string{ConversionDataAnnotation: "{"},
+		},
+	}
+	if err := ConvertFromV1alpha1(in, &MySql{}); err == nil {
+		t.Error("expected an error for a corrupt conversion annotation")
+	}
+}
@@ -0,0 +1,16 @@
+package v1beta1
+
+import (
+	"github.com/ghodss/yaml"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// CustomResourceDefinition returns the MySql CRD, including the OpenAPI schema
+// generated from the types in this package.
+func CustomResourceDefinition() (*apiextensionsv1.CustomResourceDefinition, error) {
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := yaml.Unmarshal([]byte(crdYAML), crd); err != nil {
+		return nil, err
+	}
+	return crd, nil
+}
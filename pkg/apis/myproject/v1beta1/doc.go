@@ -0,0 +1,5 @@
+// +k8s:deepcopy-gen=package,register
+
+// Package v1beta1 is the v1beta1 version of the API.
+// +groupName=myproject.io
+package v1beta1
@@ -0,0 +1,392 @@
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:path=mysqls,singular=mysql,scope=Namespaced
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.spec.version`
+// +kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.image`,priority=1
+// +kubebuilder:printcolumn:name="External-Address",type=string,JSONPath=`.status.externalAddress`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// MySql is a MySQL server managed by the operator.
+type MySql struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              MySqlSpec   `json:"spec"`
+	Status            MySqlStatus `json:"status,omitempty"`
+}
+
+// MySqlSpec is the desired state of a MySql.
+type MySqlSpec struct {
+	// Version of MySQL to run, used to pick the mysql image when Image is not
+	// set.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+){0,2}$`
+	Version string `json:"version,omitempty"`
+	// Container image to run. Takes precedence over Version. Defaults to the
+	// operator's configured image.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^[^\s]+$`
+	Image string `json:"image,omitempty"`
+	// The volume holding the instance's data.
+	// +optional
+	Storage StorageSpec `json:"storage,omitempty"`
+	// Compute resources of the MySQL container.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Which nodes the pod may run on.
+	// +optional
+	Scheduling SchedulingSpec `json:"scheduling,omitempty"`
+	// Security settings of the pod. The operator's hardened defaults pass
+	// the restricted Pod Security Standard.
+	// +optional
+	Security SecuritySpec `json:"security,omitempty"`
+	// Additions to the pod the operator runs MySQL in.
+	// +optional
+	PodTemplate PodTemplateSpec `json:"podTemplate,omitempty"`
+	// The PodDisruptionBudget limiting evictions of the instance's pods.
+	// +optional
+	DisruptionBudget DisruptionBudgetSpec `json:"disruptionBudget,omitempty"`
+	// Restrict who may connect to the instance. Anyone in the cluster may by
+	// default.
+	// +optional
+	NetworkPolicy NetworkPolicySpec `json:"networkPolicy,omitempty"`
+	// Credentials the server is set up with.
+	Credentials CredentialsSpec `json:"credentials"`
+	// The Service clients connect through. Defaults to a headless ClusterIP
+	// Service on port 3306.
+	// +optional
+	Service ServiceSpec `json:"service,omitempty"`
+	// TLS for client connections. Off by default.
+	// +optional
+	TLS TLSSpec `json:"tls,omitempty"`
+	// Stop the operator from creating or changing the objects backing the
+	// instance until this is cleared. Deleting a paused MySql still carries
+	// out its deletion policy.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+	// What happens to the instance's volume when the MySql is deleted.
+	// Defaults to Delete.
+	// +optional
+	// +kubebuilder:default=Delete
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// Refuse to delete the MySql or its volume until this is cleared.
+	// +optional
+	// +kubebuilder:default=false
+	DeletionProtection bool `json:"deletionProtection,omitempty"`
+}
+
+// StorageSpec describes the PersistentVolumeClaim backing an instance.
+type StorageSpec struct {
+	// Requested size of the volume. Defaults to the operator's configured
+	// size. It can be grown, if the storage class allows expansion, but not
+	// shrunk.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+	// Storage class of the volume. Defaults to the cluster's default class.
+	// Cannot be changed once the instance is created.
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+	// Zone the volume and the pod are placed in. Cannot be changed once the
+	// instance is created.
+	// +optional
+	Zone string `json:"zone,omitempty"`
+}
+
+// CredentialsSpec says where the passwords of an instance come from. Exactly
+// one of RootPasswordSecretRef and RootPassword is set.
+type CredentialsSpec struct {
+	// Key of a Secret, in the MySql's namespace, holding the password of the
+	// MySQL root user.
+	// +optional
+	RootPasswordSecretRef *corev1.SecretKeySelector `json:"rootPasswordSecretRef,omitempty"`
+	// Password of the MySQL root user in plain text. Only meant for objects
+	// created through v1alpha1, use RootPasswordSecretRef instead.
+	// +optional
+	RootPassword string `json:"rootPassword,omitempty"`
+}
+
+// ServiceSpec describes the Service in front of an instance. Changes are
+// applied to the existing Service, except that switching between ClusterIP
+// and the other types recreates it.
+type ServiceSpec struct {
+	// Type of the Service. A ClusterIP Service is headless. Defaults to
+	// ClusterIP.
+	// +optional
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	Type corev1.ServiceType `json:"type,omitempty"`
+	// Ports the Service exposes MySQL on. Defaults to a single port 3306.
+	// +optional
+	Ports []ServicePort `json:"ports,omitempty"`
+	// Annotations put on the Service, for example to configure a cloud load
+	// balancer.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Client CIDRs allowed through a LoadBalancer Service. Defaults to any.
+	// +optional
+	LoadBalancerSourceRanges []string `json:"loadBalancerSourceRanges,omitempty"`
+	// Whether a NodePort or LoadBalancer Service only routes external traffic
+	// to the node the server runs on, preserving client addresses. Defaults to
+	// Cluster.
+	// +optional
+	// +kubebuilder:validation:Enum=Cluster;Local
+	ExternalTrafficPolicy corev1.ServiceExternalTrafficPolicyType `json:"externalTrafficPolicy,omitempty"`
+}
+
+// ServicePort is a port of the Service, forwarding to the MySQL server.
+type ServicePort struct {
+	// Name of the port. Required when there is more than one.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+	// Port on every node of a NodePort or LoadBalancer Service. Allocated
+	// when not set.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	NodePort int32 `json:"nodePort,omitempty"`
+}
+
+// TLSSpec configures TLS for client connections. The CA clients should trust
+// is published in the "<name>-ca" Secret under ca.crt.
+type TLSSpec struct {
+	// Serve TLS to clients.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Name of a kubernetes.io/tls Secret, in the MySql's namespace, holding
+	// the server certificate and key, and the CA that signed them under
+	// ca.crt. When not set the operator generates a CA and a certificate in
+	// the "<name>-tls" Secret and replaces the certificate before it expires.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+	// Refuse client connections that do not use TLS. Needs MySQL 5.7 or
+	// later.
+	// +optional
+	RequireSecureTransport bool `json:"requireSecureTransport,omitempty"`
+}
+
+// SchedulingSpec places the pod of an instance. The fields are copied to the
+// pod spec as they are, except Affinity, which is combined with the node
+// affinity implied by the storage zone.
+type SchedulingSpec struct {
+	// Labels a node needs to run the pod.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Taints the pod tolerates.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// Node and pod affinity of the pod. When there is more than one pod
+	// and no pod anti-affinity is given, pods of the same instance prefer
+	// different nodes.
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// How pods spread across topology domains. Needs the EvenPodsSpread
+	// feature gate before Kubernetes 1.18.
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+	// Priority class of the pod.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// RuntimeClass to run the pod with.
+	// +optional
+	RuntimeClassName *string `json:"runtimeClassName,omitempty"`
+}
+
+// SecuritySpec overrides the operator's hardened security settings. The pod
+// runs as the image's mysql user, uid and gid 999, with all capabilities
+// dropped, no privilege escalation, the runtime's default seccomp profile
+// and a read-only root filesystem. Fields set here replace the matching
+// default, the rest are kept.
+type SecuritySpec struct {
+	// Merged over the default pod security context.
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
+	// Merged over the default security context of the MySQL container.
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	ContainerSecurityContext *corev1.SecurityContext `json:"containerSecurityContext,omitempty"`
+	// Seccomp profile of the pod and its MySQL container: runtime/default,
+	// docker/default, unconfined or localhost/<path>. Defaults to
+	// runtime/default.
+	// +optional
+	SeccompProfile string `json:"seccompProfile,omitempty"`
+}
+
+// PodTemplateSpec adds to the pod the operator builds, for sidecars such as
+// log shippers or backup agents and for extra configuration. The operator's
+// own container and volumes cannot be removed or replaced.
+type PodTemplateSpec struct {
+	// Containers run next to the MySQL container.
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=array
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Containers []corev1.Container `json:"containers,omitempty"`
+	// Containers run before the MySQL container starts.
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=array
+	// +kubebuilder:pruning:PreserveUnknownFields
+	InitContainers []corev1.Container `json:"initContainers,omitempty"`
+	// Volumes added to the pod.
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=array
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Volumes []corev1.Volume `json:"volumes,omitempty"`
+	// Mounts added to the MySQL container.
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=array
+	// +kubebuilder:pruning:PreserveUnknownFields
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+	// Environment variables added to the MySQL container.
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=array
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Env []corev1.EnvVar `json:"env,omitempty"`
+	// A strategic merge patch of the pod template, applied after everything
+	// else. The MySQL container is named mysql-ctr.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Override *runtime.RawExtension `json:"override,omitempty"`
+}
+
+// DisruptionBudgetSpec configures the PodDisruptionBudget of an instance.
+// Instances with more than one pod always allow one of them to be evicted
+// at a time.
+type DisruptionBudgetSpec struct {
+	// How many pods of an instance with a single pod may be evicted at once.
+	// 1, the default, lets a node drain evict the pod after the others it
+	// is waiting on; 0 blocks drains until it is raised.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=1
+	MaxUnavailable *int32 `json:"maxUnavailable,omitempty"`
+}
+
+// NetworkPolicySpec configures the NetworkPolicy of an instance.
+type NetworkPolicySpec struct {
+	// Sources allowed to connect to MySQL on port 3306, in addition to the
+	// operator. When empty the instance has no NetworkPolicy.
+	// +optional
+	AllowFrom []NetworkPolicyPeer `json:"allowFrom,omitempty"`
+	// Sources allowed to connect to the ports of containers added through
+	// spec.podTemplate, such as a metrics exporter, in addition to the
+	// operator. Defaults to allowFrom.
+	// +optional
+	SidecarAllowFrom []NetworkPolicyPeer `json:"sidecarAllowFrom,omitempty"`
+}
+
+// NetworkPolicyPeer selects pods allowed to connect, like the peers of a
+// NetworkPolicy rule. A peer with only a pod selector matches pods in the
+// instance's namespace, one with only a namespace selector every pod in the
+// matching namespaces, and one with both the matching pods in the matching
+// namespaces.
+type NetworkPolicyPeer struct {
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+}
+
+// DeletionPolicy decides what happens to the PersistentVolumeClaim of an
+// instance when the MySql is deleted.
+// +kubebuilder:validation:Enum=Delete;Retain;Snapshot
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the claim along with everything else.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain leaves the claim behind, labelled so that a new
+	// MySql with the same name adopts it.
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicySnapshot takes a VolumeSnapshot of the claim and deletes
+	// the claim once the snapshot is ready to use.
+	DeletionPolicySnapshot DeletionPolicy = "Snapshot"
+)
+
+// MySqlPhase is a short, machine readable summary of where an instance is in
+// its lifecycle.
+// +kubebuilder:validation:Enum=Pending;Running;Failed;Deleting;Paused
+type MySqlPhase string
+
+const (
+	// MySqlPhasePending means the operator has seen the instance but has not
+	// finished creating its resources.
+	MySqlPhasePending MySqlPhase = "Pending"
+	// MySqlPhaseRunning means every resource backing the instance was created.
+	MySqlPhaseRunning MySqlPhase = "Running"
+	// MySqlPhaseFailed means the last attempt to reconcile the instance failed.
+	MySqlPhaseFailed MySqlPhase = "Failed"
+	// MySqlPhaseDeleting means the MySql was deleted and the operator is
+	// carrying out its deletion policy.
+	MySqlPhaseDeleting MySqlPhase = "Deleting"
+	// MySqlPhasePaused means the operator leaves the instance alone until
+	// spec.paused is cleared.
+	MySqlPhasePaused MySqlPhase = "Paused"
+)
+
+// MySqlConditionType is the kind of a MySqlCondition.
+type MySqlConditionType string
+
+const (
+	// MySqlConditionPaused is true while the operator makes no changes to
+	// the instance.
+	MySqlConditionPaused MySqlConditionType = "Paused"
+)
+
+// MySqlCondition is an observation about one aspect of a MySql.
+type MySqlCondition struct {
+	Type   MySqlConditionType     `json:"type"`
+	Status corev1.ConditionStatus `json:"status"`
+	// When the status last changed.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Machine readable reason for the last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// MySqlStatus is the state of a MySql as last observed by the operator.
+type MySqlStatus struct {
+	Phase   MySqlPhase `json:"phase,omitempty"`
+	Message string     `json:"message,omitempty"`
+	// Address of a LoadBalancer Service, once the cloud provider has assigned
+	// one.
+	ExternalAddress string `json:"externalAddress,omitempty"`
+	// +optional
+	Conditions []MySqlCondition `json:"conditions,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MySqlList is a list of MySql objects.
+type MySqlList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []MySql `json:"items"`
+}
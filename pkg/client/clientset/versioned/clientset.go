@@ -14,11 +14,16 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
+// Code generated by client-gen. DO NOT EDIT.
+
 package versioned
 
 import (
-	glog "github.com/golang/glog"
+	fmt "fmt"
+	http "net/http"
+
 	myprojectv1alpha1 "github.com/tonya11en/mysql-operator/pkg/client/clientset/versioned/typed/myproject/v1alpha1"
+	myprojectv1beta1 "github.com/tonya11en/mysql-operator/pkg/client/clientset/versioned/typed/myproject/v1beta1"
 	discovery "k8s.io/client-go/discovery"
 	rest "k8s.io/client-go/rest"
 	flowcontrol "k8s.io/client-go/util/flowcontrol"
@@ -27,15 +32,14 @@ import (
 type Interface interface {
 	Discovery() discovery.DiscoveryInterface
 	MyprojectV1alpha1() myprojectv1alpha1.MyprojectV1alpha1Interface
-	// Deprecated: please explicitly pick a version if possible.
-	Myproject() myprojectv1alpha1.MyprojectV1alpha1Interface
+	MyprojectV1beta1() myprojectv1beta1.MyprojectV1beta1Interface
 }
 
-// Clientset contains the clients for groups. Each group has exactly one
-// version included in a Clientset.
+// Clientset contains the clients for groups.
 type Clientset struct {
 	*discovery.DiscoveryClient
 	myprojectV1alpha1 *myprojectv1alpha1.MyprojectV1alpha1Client
+	myprojectV1beta1  *myprojectv1beta1.MyprojectV1beta1Client
 }
 
 // MyprojectV1alpha1 retrieves the MyprojectV1alpha1Client
@@ -43,10 +47,9 @@ func (c *Clientset) MyprojectV1alpha1() myprojectv1alpha1.MyprojectV1alpha1Inter
 	return c.myprojectV1alpha1
 }
 
-// Deprecated: Myproject retrieves the default version of MyprojectClient.
-// Please explicitly pick a version.
-func (c *Clientset) Myproject() myprojectv1alpha1.MyprojectV1alpha1Interface {
-	return c.myprojectV1alpha1
+// MyprojectV1beta1 retrieves the MyprojectV1beta1Client
+func (c *Clientset) MyprojectV1beta1() myprojectv1beta1.MyprojectV1beta1Interface {
+	return c.myprojectV1beta1
 }
 
 // Discovery retrieves the DiscoveryClient
@@ -58,21 +61,52 @@ func (c *Clientset) Discovery() discovery.DiscoveryInterface {
 }
 
 // NewForConfig creates a new Clientset for the given config.
+// If config's RateLimiter is not set and QPS and Burst are acceptable,
+// NewForConfig will generate a rate-limiter in configShallowCopy.
+// NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
+// where httpClient was generated with rest.HTTPClientFor(c).
 func NewForConfig(c *rest.Config) (*Clientset, error) {
 	configShallowCopy := *c
+
+	if configShallowCopy.UserAgent == "" {
+		configShallowCopy.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+
+	// share the transport between all clients
+	httpClient, err := rest.HTTPClientFor(&configShallowCopy)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewForConfigAndClient(&configShallowCopy, httpClient)
+}
+
+// NewForConfigAndClient creates a new Clientset for the given config and http client.
+// Note the http client provided takes precedence over the configured transport values.
+// If config's RateLimiter is not set and QPS and Burst are acceptable,
+// NewForConfigAndClient will generate a rate-limiter in configShallowCopy.
+func NewForConfigAndClient(c *rest.Config, httpClient *http.Client) (*Clientset, error) {
+	configShallowCopy := *c
 	if configShallowCopy.RateLimiter == nil && configShallowCopy.QPS > 0 {
+		if configShallowCopy.Burst <= 0 {
+			return nil, fmt.Errorf("burst is required to be greater than 0 when RateLimiter is not set and QPS is set to greater than 0")
+		}
 		configShallowCopy.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(configShallowCopy.QPS, configShallowCopy.Burst)
 	}
+
 	var cs Clientset
 	var err error
-	cs.myprojectV1alpha1, err = myprojectv1alpha1.NewForConfig(&configShallowCopy)
+	cs.myprojectV1alpha1, err = myprojectv1alpha1.NewForConfigAndClient(&configShallowCopy, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	cs.myprojectV1beta1, err = myprojectv1beta1.NewForConfigAndClient(&configShallowCopy, httpClient)
 	if err != nil {
 		return nil, err
 	}
 
-	cs.DiscoveryClient, err = discovery.NewDiscoveryClientForConfig(&configShallowCopy)
+	cs.DiscoveryClient, err = discovery.NewDiscoveryClientForConfigAndClient(&configShallowCopy, httpClient)
 	if err != nil {
-		glog.Errorf("failed to create the DiscoveryClient: %v", err)
 		return nil, err
 	}
 	return &cs, nil
@@ -81,17 +115,18 @@ func NewForConfig(c *rest.Config) (*Clientset, error) {
 // NewForConfigOrDie creates a new Clientset for the given config and
 // panics if there is an error in the config.
 func NewForConfigOrDie(c *rest.Config) *Clientset {
-	var cs Clientset
-	cs.myprojectV1alpha1 = myprojectv1alpha1.NewForConfigOrDie(c)
-
-	cs.DiscoveryClient = discovery.NewDiscoveryClientForConfigOrDie(c)
-	return &cs
+	cs, err := NewForConfig(c)
+	if err != nil {
+		panic(err)
+	}
+	return cs
 }
 
 // New creates a new Clientset for the given RESTClient.
 func New(c rest.Interface) *Clientset {
 	var cs Clientset
 	cs.myprojectV1alpha1 = myprojectv1alpha1.New(c)
+	cs.myprojectV1beta1 = myprojectv1beta1.New(c)
 
 	cs.DiscoveryClient = discovery.NewDiscoveryClient(c)
 	return &cs
@@ -14,6 +14,8 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
+// Code generated by client-gen. DO NOT EDIT.
+
 package fake
 
 import (
@@ -27,7 +29,7 @@ type FakeMyprojectV1alpha1 struct {
 }
 
 func (c *FakeMyprojectV1alpha1) MySqls(namespace string) v1alpha1.MySqlInterface {
-	return &FakeMySqls{c, namespace}
+	return newFakeMySqls(c, namespace)
 }
 
 // RESTClient returns a RESTClient that is used to communicate
@@ -14,113 +14,35 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
+// Code generated by client-gen. DO NOT EDIT.
+
 package fake
 
 import (
 	v1alpha1 "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1alpha1"
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	labels "k8s.io/apimachinery/pkg/labels"
-	schema "k8s.io/apimachinery/pkg/runtime/schema"
-	types "k8s.io/apimachinery/pkg/types"
-	watch "k8s.io/apimachinery/pkg/watch"
-	testing "k8s.io/client-go/testing"
+	myprojectv1alpha1 "github.com/tonya11en/mysql-operator/pkg/client/clientset/versioned/typed/myproject/v1alpha1"
+	gentype "k8s.io/client-go/gentype"
 )
 
-// FakeMySqls implements MySqlInterface
-type FakeMySqls struct {
+// fakeMySqls implements MySqlInterface
+type fakeMySqls struct {
+	*gentype.FakeClientWithList[*v1alpha1.MySql, *v1alpha1.MySqlList]
 	Fake *FakeMyprojectV1alpha1
-	ns   string
-}
-
-var mysqlsResource = schema.GroupVersionResource{Group: "myproject", Version: "v1alpha1", Resource: "mysqls"}
-
-var mysqlsKind = schema.GroupVersionKind{Group: "myproject", Version: "v1alpha1", Kind: "MySql"}
-
-// Get takes name of the mySql, and returns the corresponding mySql object, and an error if there is any.
-func (c *FakeMySqls) Get(name string, options v1.GetOptions) (result *v1alpha1.MySql, err error) {
-	obj, err := c.Fake.
-		Invokes(testing.NewGetAction(mysqlsResource, c.ns, name), &v1alpha1.MySql{})
-
-	if obj == nil {
-		return nil, err
-	}
-	return obj.(*v1alpha1.MySql), err
-}
-
-// List takes label and field selectors, and returns the list of MySqls that match those selectors.
-func (c *FakeMySqls) List(opts v1.ListOptions) (result *v1alpha1.MySqlList, err error) {
-	obj, err := c.Fake.
-		Invokes(testing.NewListAction(mysqlsResource, mysqlsKind, c.ns, opts), &v1alpha1.MySqlList{})
-
-	if obj == nil {
-		return nil, err
-	}
-
-	label, _, _ := testing.ExtractFromListOptions(opts)
-	if label == nil {
-		label = labels.Everything()
-	}
-	list := &v1alpha1.MySqlList{}
-	for _, item := range obj.(*v1alpha1.MySqlList).Items {
-		if label.Matches(labels.Set(item.Labels)) {
-			list.Items = append(list.Items, item)
-		}
-	}
-	return list, err
-}
-
-// Watch returns a watch.Interface that watches the requested mySqls.
-func (c *FakeMySqls) Watch(opts v1.ListOptions) (watch.Interface, error) {
-	return c.Fake.
-		InvokesWatch(testing.NewWatchAction(mysqlsResource, c.ns, opts))
-
-}
-
-// Create takes the representation of a mySql and creates it.  Returns the server's representation of the mySql, and an error, if there is any.
-func (c *FakeMySqls) Create(mySql *v1alpha1.MySql) (result *v1alpha1.MySql, err error) {
-	obj, err := c.Fake.
-		Invokes(testing.NewCreateAction(mysqlsResource, c.ns, mySql), &v1alpha1.MySql{})
-
-	if obj == nil {
-		return nil, err
-	}
-	return obj.(*v1alpha1.MySql), err
-}
-
-// Update takes the representation of a mySql and updates it. Returns the server's representation of the mySql, and an error, if there is any.
-func (c *FakeMySqls) Update(mySql *v1alpha1.MySql) (result *v1alpha1.MySql, err error) {
-	obj, err := c.Fake.
-		Invokes(testing.NewUpdateAction(mysqlsResource, c.ns, mySql), &v1alpha1.MySql{})
-
-	if obj == nil {
-		return nil, err
-	}
-	return obj.(*v1alpha1.MySql), err
-}
-
-// Delete takes name of the mySql and deletes it. Returns an error if one occurs.
-func (c *FakeMySqls) Delete(name string, options *v1.DeleteOptions) error {
-	_, err := c.Fake.
-		Invokes(testing.NewDeleteAction(mysqlsResource, c.ns, name), &v1alpha1.MySql{})
-
-	return err
 }
 
-// DeleteCollection deletes a collection of objects.
-func (c *FakeMySqls) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
-	action := testing.NewDeleteCollectionAction(mysqlsResource, c.ns, listOptions)
-
-	_, err := c.Fake.Invokes(action, &v1alpha1.MySqlList{})
-	return err
-}
-
-// Patch applies the patch and returns the patched mySql.
-func (c *FakeMySqls) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.MySql, err error) {
-	obj, err := c.Fake.
-		Invokes(testing.NewPatchSubresourceAction(mysqlsResource, c.ns, name, data, subresources...), &v1alpha1.MySql{})
-
-	if obj == nil {
-		return nil, err
+func newFakeMySqls(fake *FakeMyprojectV1alpha1, namespace string) myprojectv1alpha1.MySqlInterface {
+	return &fakeMySqls{
+		gentype.NewFakeClientWithList[*v1alpha1.MySql, *v1alpha1.MySqlList](
+			fake.Fake,
+			namespace,
+			v1alpha1.SchemeGroupVersion.WithResource("mysqls"),
+			v1alpha1.SchemeGroupVersion.WithKind("MySql"),
+			func() *v1alpha1.MySql { return &v1alpha1.MySql{} },
+			func() *v1alpha1.MySqlList { return &v1alpha1.MySqlList{} },
+			func(dst, src *v1alpha1.MySqlList) { dst.ListMeta = src.ListMeta },
+			func(list *v1alpha1.MySqlList) []*v1alpha1.MySql { return gentype.ToPointerSlice(list.Items) },
+			func(list *v1alpha1.MySqlList, items []*v1alpha1.MySql) { list.Items = gentype.FromPointerSlice(items) },
+		),
+		fake,
 	}
-	return obj.(*v1alpha1.MySql), err
 }
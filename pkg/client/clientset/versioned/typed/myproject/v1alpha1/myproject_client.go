@@ -14,12 +14,15 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
+// Code generated by client-gen. DO NOT EDIT.
+
 package v1alpha1
 
 import (
-	v1alpha1 "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1alpha1"
-	"github.com/tonya11en/mysql-operator/pkg/client/clientset/versioned/scheme"
-	serializer "k8s.io/apimachinery/pkg/runtime/serializer"
+	http "net/http"
+
+	myprojectv1alpha1 "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1alpha1"
+	scheme "github.com/tonya11en/mysql-operator/pkg/client/clientset/versioned/scheme"
 	rest "k8s.io/client-go/rest"
 )
 
@@ -28,7 +31,7 @@ type MyprojectV1alpha1Interface interface {
 	MySqlsGetter
 }
 
-// MyprojectV1alpha1Client is used to interact with features provided by the myproject group.
+// MyprojectV1alpha1Client is used to interact with features provided by the myproject.io group.
 type MyprojectV1alpha1Client struct {
 	restClient rest.Interface
 }
@@ -38,12 +41,24 @@ func (c *MyprojectV1alpha1Client) MySqls(namespace string) MySqlInterface {
 }
 
 // NewForConfig creates a new MyprojectV1alpha1Client for the given config.
+// NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
+// where httpClient was generated with rest.HTTPClientFor(c).
 func NewForConfig(c *rest.Config) (*MyprojectV1alpha1Client, error) {
 	config := *c
-	if err := setConfigDefaults(&config); err != nil {
+	setConfigDefaults(&config)
+	httpClient, err := rest.HTTPClientFor(&config)
+	if err != nil {
 		return nil, err
 	}
-	client, err := rest.RESTClientFor(&config)
+	return NewForConfigAndClient(&config, httpClient)
+}
+
+// NewForConfigAndClient creates a new MyprojectV1alpha1Client for the given config and http client.
+// Note the http client provided takes precedence over the configured transport values.
+func NewForConfigAndClient(c *rest.Config, h *http.Client) (*MyprojectV1alpha1Client, error) {
+	config := *c
+	setConfigDefaults(&config)
+	client, err := rest.RESTClientForConfigAndClient(&config, h)
 	if err != nil {
 		return nil, err
 	}
@@ -65,17 +80,15 @@ func New(c rest.Interface) *MyprojectV1alpha1Client {
 	return &MyprojectV1alpha1Client{c}
 }
 
-func setConfigDefaults(config *rest.Config) error {
-	gv := v1alpha1.SchemeGroupVersion
+func setConfigDefaults(config *rest.Config) {
+	gv := myprojectv1alpha1.SchemeGroupVersion
 	config.GroupVersion = &gv
 	config.APIPath = "/apis"
-	config.NegotiatedSerializer = serializer.DirectCodecFactory{CodecFactory: scheme.Codecs}
+	config.NegotiatedSerializer = rest.CodecFactoryForGeneratedClient(scheme.Scheme, scheme.Codecs).WithoutConversion()
 
 	if config.UserAgent == "" {
 		config.UserAgent = rest.DefaultKubernetesUserAgent()
 	}
-
-	return nil
 }
 
 // RESTClient returns a RESTClient that is used to communicate
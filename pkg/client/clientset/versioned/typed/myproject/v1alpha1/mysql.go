@@ -14,15 +14,19 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
+// Code generated by client-gen. DO NOT EDIT.
+
 package v1alpha1
 
 import (
-	v1alpha1 "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1alpha1"
+	context "context"
+
+	myprojectv1alpha1 "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1alpha1"
 	scheme "github.com/tonya11en/mysql-operator/pkg/client/clientset/versioned/scheme"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	types "k8s.io/apimachinery/pkg/types"
 	watch "k8s.io/apimachinery/pkg/watch"
-	rest "k8s.io/client-go/rest"
+	gentype "k8s.io/client-go/gentype"
 )
 
 // MySqlsGetter has a method to return a MySqlInterface.
@@ -33,123 +37,32 @@ type MySqlsGetter interface {
 
 // MySqlInterface has methods to work with MySql resources.
 type MySqlInterface interface {
-	Create(*v1alpha1.MySql) (*v1alpha1.MySql, error)
-	Update(*v1alpha1.MySql) (*v1alpha1.MySql, error)
-	Delete(name string, options *v1.DeleteOptions) error
-	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
-	Get(name string, options v1.GetOptions) (*v1alpha1.MySql, error)
-	List(opts v1.ListOptions) (*v1alpha1.MySqlList, error)
-	Watch(opts v1.ListOptions) (watch.Interface, error)
-	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.MySql, err error)
+	Create(ctx context.Context, mySql *myprojectv1alpha1.MySql, opts v1.CreateOptions) (*myprojectv1alpha1.MySql, error)
+	Update(ctx context.Context, mySql *myprojectv1alpha1.MySql, opts v1.UpdateOptions) (*myprojectv1alpha1.MySql, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*myprojectv1alpha1.MySql, error)
+	List(ctx context.Context, opts v1.ListOptions) (*myprojectv1alpha1.MySqlList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *myprojectv1alpha1.MySql, err error)
 	MySqlExpansion
 }
 
 // mySqls implements MySqlInterface
 type mySqls struct {
-	client rest.Interface
-	ns     string
+	*gentype.ClientWithList[*myprojectv1alpha1.MySql, *myprojectv1alpha1.MySqlList]
 }
 
 // newMySqls returns a MySqls
 func newMySqls(c *MyprojectV1alpha1Client, namespace string) *mySqls {
 	return &mySqls{
-		client: c.RESTClient(),
-		ns:     namespace,
+		gentype.NewClientWithList[*myprojectv1alpha1.MySql, *myprojectv1alpha1.MySqlList](
+			"mysqls",
+			c.RESTClient(),
+			scheme.ParameterCodec,
+			namespace,
+			func() *myprojectv1alpha1.MySql { return &myprojectv1alpha1.MySql{} },
+			func() *myprojectv1alpha1.MySqlList { return &myprojectv1alpha1.MySqlList{} },
+		),
 	}
 }
-
-// Get takes name of the mySql, and returns the corresponding mySql object, and an error if there is any.
-func (c *mySqls) Get(name string, options v1.GetOptions) (result *v1alpha1.MySql, err error) {
-	result = &v1alpha1.MySql{}
-	err = c.client.Get().
-		Namespace(c.ns).
-		Resource("mysqls").
-		Name(name).
-		VersionedParams(&options, scheme.ParameterCodec).
-		Do().
-		Into(result)
-	return
-}
-
-// List takes label and field selectors, and returns the list of MySqls that match those selectors.
-func (c *mySqls) List(opts v1.ListOptions) (result *v1alpha1.MySqlList, err error) {
-	result = &v1alpha1.MySqlList{}
-	err = c.client.Get().
-		Namespace(c.ns).
-		Resource("mysqls").
-		VersionedParams(&opts, scheme.ParameterCodec).
-		Do().
-		Into(result)
-	return
-}
-
-// Watch returns a watch.Interface that watches the requested mySqls.
-func (c *mySqls) Watch(opts v1.ListOptions) (watch.Interface, error) {
-	opts.Watch = true
-	return c.client.Get().
-		Namespace(c.ns).
-		Resource("mysqls").
-		VersionedParams(&opts, scheme.ParameterCodec).
-		Watch()
-}
-
-// Create takes the representation of a mySql and creates it.  Returns the server's representation of the mySql, and an error, if there is any.
-func (c *mySqls) Create(mySql *v1alpha1.MySql) (result *v1alpha1.MySql, err error) {
-	result = &v1alpha1.MySql{}
-	err = c.client.Post().
-		Namespace(c.ns).
-		Resource("mysqls").
-		Body(mySql).
-		Do().
-		Into(result)
-	return
-}
-
-// Update takes the representation of a mySql and updates it. Returns the server's representation of the mySql, and an error, if there is any.
-func (c *mySqls) Update(mySql *v1alpha1.MySql) (result *v1alpha1.MySql, err error) {
-	result = &v1alpha1.MySql{}
-	err = c.client.Put().
-		Namespace(c.ns).
-		Resource("mysqls").
-		Name(mySql.Name).
-		Body(mySql).
-		Do().
-		Into(result)
-	return
-}
-
-// Delete takes name of the mySql and deletes it. Returns an error if one occurs.
-func (c *mySqls) Delete(name string, options *v1.DeleteOptions) error {
-	return c.client.Delete().
-		Namespace(c.ns).
-		Resource("mysqls").
-		Name(name).
-		Body(options).
-		Do().
-		Error()
-}
-
-// DeleteCollection deletes a collection of objects.
-func (c *mySqls) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
-	return c.client.Delete().
-		Namespace(c.ns).
-		Resource("mysqls").
-		VersionedParams(&listOptions, scheme.ParameterCodec).
-		Body(options).
-		Do().
-		Error()
-}
-
-// Patch applies the patch and returns the patched mySql.
-func (c *mySqls) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.MySql, err error) {
-	result = &v1alpha1.MySql{}
-	err = c.client.Patch(pt).
-		Namespace(c.ns).
-		Resource("mysqls").
-		SubResource(subresources...).
-		Name(name).
-		Body(data).
-		Do().
-		Into(result)
-	return
-}
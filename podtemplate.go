@@ -0,0 +1,164 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	"k8s.io/api/core/v1"
+)
+
+// Names of the operator's own parts of the pod.
+const (
+	mysqlContainerName = "mysql-ctr"
+	dataVolumeName     = "mysql-persistent-storage"
+	dataMountPath      = "/var/lib/mysql"
+)
+
+// The pod template carries a hash of spec.podTemplate. When it changes the
+// pod spec is replaced as a whole, so containers and volumes taken out of
+// spec.podTemplate are removed along with it.
+const podTemplateHashAnnotation = "myproject.io/pod-template-hash"
+
+// Add the containers, volumes, mounts and environment of spec.podTemplate to
+// the pod, then apply its override.
+func customizePod(template *v1.PodTemplateSpec, s *mysql.MySql) error {
+	spec := s.Spec.PodTemplate.DeepCopy()
+	kept := volumeNames(template.Spec.Volumes)
+
+	container := &template.Spec.Containers[0]
+	container.Env = append(container.Env, spec.Env...)
+	container.VolumeMounts = append(container.VolumeMounts, spec.VolumeMounts...)
+	template.Spec.Volumes = append(template.Spec.Volumes, spec.Volumes...)
+	template.Spec.Containers = append(template.Spec.Containers, spec.Containers...)
+	template.Spec.InitContainers = append(template.Spec.InitContainers, spec.InitContainers...)
+
+	if spec.Override != nil && len(spec.Override.Raw) > 0 {
+		if err := strategicMerge(template, spec.Override.Raw); err != nil {
+			return fmt.Errorf("failed to apply spec.podTemplate.override. %+v", err)
+		}
+	}
+	if err := checkOperatorParts(template, kept); err != nil {
+		return err
+	}
+	setPodDefaults(&template.Spec)
+
+	hash, err := podTemplateHash(spec)
+	if err != nil {
+		return err
+	}
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[podTemplateHashAnnotation] = hash
+	return nil
+}
+
+// The override may change the operator's container and volumes but not
+// remove them.
+func checkOperatorParts(template *v1.PodTemplateSpec, volumes []string) error {
+	if len(template.Spec.Containers) == 0 || template.Spec.Containers[0].Name != mysqlContainerName {
+		return fmt.Errorf("spec.podTemplate.override must keep the %s container first", mysqlContainerName)
+	}
+	for _, name := range volumes {
+		if volumeIndex(template.Spec.Volumes, name) < 0 {
+			return fmt.Errorf("spec.podTemplate.override must keep the %s volume", name)
+		}
+	}
+	return nil
+}
+
+// The volumes the operator may add to the pod of a MySql, and where their
+// mounts go.
+func operatorVolumes(s *mysql.MySql) map[string]string {
+	volumes := map[string]string{
+		dataVolumeName: dataMountPath,
+		tlsVolumeName:  tlsMountPath,
+	}
+	for _, dir := range writableDirs {
+		volumes[dir.volume] = dir.path
+	}
+	return volumes
+}
+
+// A pod with only the operator's own container and volumes, to check an
+// override against without building the whole pod.
+func operatorSkeleton(s *mysql.MySql) *v1.PodTemplateSpec {
+	template := &v1.PodTemplateSpec{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: mysqlContainerName}},
+		},
+	}
+	for name := range operatorVolumes(s) {
+		template.Spec.Volumes = append(template.Spec.Volumes, v1.Volume{Name: name})
+	}
+	return template
+}
+
+func volumeNames(volumes []v1.Volume) []string {
+	names := make([]string, len(volumes))
+	for i := range volumes {
+		names[i] = volumes[i].Name
+	}
+	return names
+}
+
+func podTemplateHash(spec *mysql.PodTemplateSpec) (string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash spec.podTemplate. %+v", err)
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:8]), nil
+}
+
+// Fill in the defaults the API server gives the fields drift compares, so a
+// user supplied container or volume that leaves them out is not seen as
+// changed on every reconcile.
+func setPodDefaults(podSpec *v1.PodSpec) {
+	mode := v1.SecretVolumeSourceDefaultMode
+	for i := range podSpec.Volumes {
+		source := &podSpec.Volumes[i].VolumeSource
+		switch {
+		case source.Secret != nil && source.Secret.DefaultMode == nil:
+			source.Secret.DefaultMode = &mode
+		case source.ConfigMap != nil && source.ConfigMap.DefaultMode == nil:
+			source.ConfigMap.DefaultMode = &mode
+		case source.DownwardAPI != nil && source.DownwardAPI.DefaultMode == nil:
+			source.DownwardAPI.DefaultMode = &mode
+		case source.Projected != nil && source.Projected.DefaultMode == nil:
+			source.Projected.DefaultMode = &mode
+		case source.HostPath != nil && source.HostPath.Type == nil:
+			pathType := v1.HostPathUnset
+			source.HostPath.Type = &pathType
+		}
+	}
+	for _, containers := range [][]v1.Container{podSpec.Containers, podSpec.InitContainers} {
+		for i := range containers {
+			for j := range containers[i].Env {
+				ref := containers[i].Env[j].ValueFrom
+				if ref != nil && ref.FieldRef != nil && ref.FieldRef.APIVersion == "" {
+					ref.FieldRef.APIVersion = "v1"
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/tonya11en/mysql-operator/pkg/apis/myproject"
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	"k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Place the pod of a MySql as its spec.scheduling asks.
+func schedulePod(podSpec *v1.PodSpec, s *mysql.MySql, replicas int32) {
+	scheduling := s.Spec.Scheduling.DeepCopy()
+	podSpec.NodeSelector = scheduling.NodeSelector
+	podSpec.Tolerations = scheduling.Tolerations
+	podSpec.TopologySpreadConstraints = scheduling.TopologySpreadConstraints
+	podSpec.PriorityClassName = scheduling.PriorityClassName
+	podSpec.RuntimeClassName = scheduling.RuntimeClassName
+	podSpec.Affinity = podAffinity(s, scheduling.Affinity, replicas)
+}
+
+// The affinity given in the spec, keeping the pod in the storage zone, if one
+// is set, and its replicas on different nodes unless the spec says otherwise.
+// The volume follows the pod when its storage class binds on first use.
+func podAffinity(s *mysql.MySql, affinity *v1.Affinity, replicas int32) *v1.Affinity {
+	if zone := s.Spec.Storage.Zone; zone != "" {
+		if affinity == nil {
+			affinity = &v1.Affinity{}
+		}
+		requireNodes(affinity, v1.NodeSelectorRequirement{
+			Key:      v1.LabelZoneFailureDomain,
+			Operator: v1.NodeSelectorOpIn,
+			Values:   []string{zone},
+		})
+	}
+
+	if replicas > 1 && (affinity == nil || affinity.PodAntiAffinity == nil) {
+		if affinity == nil {
+			affinity = &v1.Affinity{}
+		}
+		affinity.PodAntiAffinity = &v1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []v1.WeightedPodAffinityTerm{
+				{
+					Weight: 100,
+					PodAffinityTerm: v1.PodAffinityTerm{
+						LabelSelector: &meta_v1.LabelSelector{
+							MatchLabels: map[string]string{myproject.InstanceLabel: s.Name},
+						},
+						TopologyKey: v1.LabelHostname,
+					},
+				},
+			},
+		}
+	}
+	return affinity
+}
+
+// Add a requirement every node has to meet. Node selector terms are ORed, so
+// it goes into each of them.
+func requireNodes(affinity *v1.Affinity, requirement v1.NodeSelectorRequirement) {
+	if affinity.NodeAffinity == nil {
+		affinity.NodeAffinity = &v1.NodeAffinity{}
+	}
+	required := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if required == nil || len(required.NodeSelectorTerms) == 0 {
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &v1.NodeSelector{
+			NodeSelectorTerms: []v1.NodeSelectorTerm{
+				{MatchExpressions: []v1.NodeSelectorRequirement{requirement}},
+			},
+		}
+		return
+	}
+	for i := range required.NodeSelectorTerms {
+		term := &required.NodeSelectorTerms[i]
+		term.MatchExpressions = append(term.MatchExpressions, requirement)
+	}
+}
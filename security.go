@@ -0,0 +1,143 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// The mysql user and group of the official image.
+const (
+	mysqlUID int64 = 999
+	mysqlGID int64 = 999
+)
+
+// Directories mysqld writes to besides its data, given their own volumes as
+// the root filesystem is read-only.
+var writableDirs = []struct {
+	volume string
+	path   string
+}{
+	{"mysql-run", "/var/run/mysqld"},
+	{"tmp", "/tmp"},
+}
+
+func defaultPodSecurityContext(seccomp *v1.SeccompProfile) *v1.PodSecurityContext {
+	uid, gid, nonRoot := mysqlUID, mysqlGID, true
+	return &v1.PodSecurityContext{
+		RunAsUser:      &uid,
+		RunAsGroup:     &gid,
+		RunAsNonRoot:   &nonRoot,
+		FSGroup:        &gid,
+		SeccompProfile: seccomp,
+	}
+}
+
+func defaultContainerSecurityContext(seccomp *v1.SeccompProfile) *v1.SecurityContext {
+	nonRoot, readOnly, escalation := true, true, false
+	return &v1.SecurityContext{
+		RunAsNonRoot:             &nonRoot,
+		ReadOnlyRootFilesystem:   &readOnly,
+		AllowPrivilegeEscalation: &escalation,
+		Capabilities: &v1.Capabilities{
+			Drop: []v1.Capability{"ALL"},
+		},
+		SeccompProfile: seccomp,
+	}
+}
+
+// The typed form of spec.security.seccompProfile, which keeps the values of
+// the old seccomp annotation. docker/default is the runtime's default under
+// its old name.
+func seccompProfile(profile string) *v1.SeccompProfile {
+	switch {
+	case profile == v1.SeccompProfileNameUnconfined:
+		return &v1.SeccompProfile{Type: v1.SeccompProfileTypeUnconfined}
+	case strings.HasPrefix(profile, v1.SeccompLocalhostProfileNamePrefix):
+		path := strings.TrimPrefix(profile, v1.SeccompLocalhostProfileNamePrefix)
+		return &v1.SeccompProfile{Type: v1.SeccompProfileTypeLocalhost, LocalhostProfile: &path}
+	}
+	return &v1.SeccompProfile{Type: v1.SeccompProfileTypeRuntimeDefault}
+}
+
+// Apply a strategic merge patch to obj, a pointer to an API struct, in place.
+func strategicMerge(obj interface{}, patch []byte) error {
+	original, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	merged, err := strategicpatch.StrategicMergePatch(original, patch, obj)
+	if err != nil {
+		return err
+	}
+	// Fields the patch removed must not survive the decode.
+	target := reflect.ValueOf(obj).Elem()
+	target.Set(reflect.Zero(target.Type()))
+	return json.Unmarshal(merged, obj)
+}
+
+// Merge the fields set in override over defaults, which is updated in place.
+func mergeOver(defaults interface{}, override interface{}) error {
+	patch, err := json.Marshal(override)
+	if err != nil {
+		return err
+	}
+	return strategicMerge(defaults, patch)
+}
+
+// Apply the hardened security settings of a MySql, with its overrides, to the
+// pod and its MySQL container.
+func securePod(podSpec *v1.PodTemplateSpec, s *mysql.MySql) error {
+	spec := s.Spec.Security
+
+	seccomp := seccompProfile(spec.SeccompProfile)
+	podContext := defaultPodSecurityContext(seccomp)
+	if spec.PodSecurityContext != nil {
+		if err := mergeOver(podContext, spec.PodSecurityContext); err != nil {
+			return fmt.Errorf("failed to merge spec.security.podSecurityContext. %+v", err)
+		}
+	}
+	podSpec.Spec.SecurityContext = podContext
+
+	container := &podSpec.Spec.Containers[0]
+	containerContext := defaultContainerSecurityContext(seccomp.DeepCopy())
+	if spec.ContainerSecurityContext != nil {
+		if err := mergeOver(containerContext, spec.ContainerSecurityContext); err != nil {
+			return fmt.Errorf("failed to merge spec.security.containerSecurityContext. %+v", err)
+		}
+	}
+	container.SecurityContext = containerContext
+
+	for _, dir := range writableDirs {
+		podSpec.Spec.Volumes = append(podSpec.Spec.Volumes, v1.Volume{
+			Name:         dir.volume,
+			VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+			Name:      dir.volume,
+			MountPath: dir.path,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/tonya11en/mysql-operator/pkg/apis/myproject"
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Where the server's certificates are mounted.
+const (
+	tlsVolumeName = "mysql-tls"
+	tlsMountPath  = "/etc/mysql/tls"
+)
+
+// The pod template carries a hash of the server certificate, so replacing
+// the certificate restarts the server to load it.
+const certHashAnnotation = "myproject.io/tls-cert-hash"
+
+// The certificate files, private key included, are only readable by their
+// group. The pod's fsGroup makes that the mysql group the server runs as.
+var tlsFileMode int32 = 0440
+
+// podTLS says where the pod of a MySql gets its certificates from.
+type podTLS struct {
+	secretName             string
+	requireSecureTransport bool
+	certHash               string
+}
+
+func getTLSSecretName(objName string) string {
+	return objName + "-tls"
+}
+
+func getCASecretName(objName string) string {
+	return objName + myproject.CASecretSuffix
+}
+
+// The names the Service of a MySql can be reached at from inside the cluster.
+func serviceDNSNames(s *mysql.MySql) []string {
+	return []string{
+		s.Name,
+		fmt.Sprintf("%s.%s", s.Name, s.Namespace),
+		fmt.Sprintf("%s.%s.svc", s.Name, s.Namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", s.Name, s.Namespace),
+	}
+}
+
+// Mount the certificates into the MySQL container and point the server at
+// them. The CA key in an operator generated Secret is left out.
+func (t *podTLS) mount(podSpec *v1.PodTemplateSpec) {
+	if podSpec.Annotations == nil {
+		podSpec.Annotations = map[string]string{}
+	}
+	podSpec.Annotations[certHashAnnotation] = t.certHash
+
+	podSpec.Spec.Volumes = append(podSpec.Spec.Volumes, v1.Volume{
+		Name: tlsVolumeName,
+		VolumeSource: v1.VolumeSource{
+			Secret: &v1.SecretVolumeSource{
+				SecretName: t.secretName,
+				Items: []v1.KeyToPath{
+					{Key: myproject.CACertKey, Path: myproject.CACertKey},
+					{Key: v1.TLSCertKey, Path: v1.TLSCertKey},
+					{Key: v1.TLSPrivateKeyKey, Path: v1.TLSPrivateKeyKey},
+				},
+				DefaultMode: &tlsFileMode,
+			},
+		},
+	})
+
+	container := &podSpec.Spec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+		Name:      tlsVolumeName,
+		MountPath: tlsMountPath,
+		ReadOnly:  true,
+	})
+	container.Args = append(container.Args,
+		"--ssl-ca="+tlsMountPath+"/"+myproject.CACertKey,
+		"--ssl-cert="+tlsMountPath+"/"+v1.TLSCertKey,
+		"--ssl-key="+tlsMountPath+"/"+v1.TLSPrivateKeyKey,
+	)
+	if t.requireSecureTransport {
+		container.Args = append(container.Args, "--require-secure-transport=ON")
+	}
+}
+
+// Make sure the certificates of a MySql with TLS enabled exist, generating
+// or renewing them unless the MySql brings its own, and publish the CA for
+// clients. Returns nil when TLS is off.
+func (c *MySqlController) syncTLS(log *zap.Logger, s *mysql.MySql) (*podTLS, error) {
+	spec := s.Spec.TLS
+	if !spec.Enabled {
+		return nil, nil
+	}
+
+	var bundle *certBundle
+	secretName := spec.SecretName
+	if secretName == "" {
+		secretName = getTLSSecretName(s.Name)
+		var err error
+		bundle, err = ensureCertSecret(c.clientset, s.Namespace, secretName, serviceDNSNames(s), managedLabels(s, nil))
+		if err != nil {
+			c.recorder.Eventf(s, v1.EventTypeWarning, reasonFailedCreate, "Failed to create certificates in Secret %s: %v", secretName, err)
+			return nil, err
+		}
+	} else {
+		secret, err := c.clientset.CoreV1().Secrets(s.Namespace).Get(context.TODO(), secretName, meta_v1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls secret %s. %+v", secretName, err)
+		}
+		bundle = bundleFromSecret(secret)
+		if len(bundle.caCert) == 0 || len(bundle.cert) == 0 || len(bundle.key) == 0 {
+			err := fmt.Errorf("tls secret %s needs %s, %s and %s", secretName, myproject.CACertKey, v1.TLSCertKey, v1.TLSPrivateKeyKey)
+			c.recorder.Event(s, v1.EventTypeWarning, reasonFailedCreate, err.Error())
+			return nil, err
+		}
+	}
+
+	if err := c.publishCA(log, s, bundle.caCert); err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(bundle.cert)
+	return &podTLS{
+		secretName:             secretName,
+		requireSecureTransport: spec.RequireSecureTransport,
+		certHash:               hex.EncodeToString(hash[:]),
+	}, nil
+}
+
+// Write the CA certificate of a MySql to the Secret clients mount to verify
+// the server.
+func (c *MySqlController) publishCA(log *zap.Logger, s *mysql.MySql, caCert []byte) error {
+	secrets := c.clientset.CoreV1().Secrets(s.Namespace)
+	name := getCASecretName(s.Name)
+	secret, err := secrets.Get(context.TODO(), name, meta_v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = secrets.Create(context.TODO(), &v1.Secret{
+			ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: s.Namespace, Labels: managedLabels(s, nil)},
+			Type:       v1.SecretTypeOpaque,
+			Data:       map[string][]byte{myproject.CACertKey: caCert},
+		}, meta_v1.CreateOptions{})
+		c.recordCreate(s, "Secret", name, err)
+		if err != nil {
+			return fmt.Errorf("failed to create ca secret. %+v", err)
+		}
+		log.Info("published ca certificate", zap.String("secret", name))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if string(secret.Data[myproject.CACertKey]) == string(caCert) {
+		return nil
+	}
+
+	secret = secret.DeepCopy()
+	secret.Data = map[string][]byte{myproject.CACertKey: caCert}
+	if _, err := secrets.Update(context.TODO(), secret, meta_v1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update ca secret. %+v", err)
+	}
+	log.Info("published new ca certificate", zap.String("secret", name))
+	return nil
+}
+
+// Record that the server is being restarted to load a new certificate.
+func (c *MySqlController) recordCertRotation(log *zap.Logger, s *mysql.MySql, live *appsv1.Deployment, tls *podTLS) {
+	if tls == nil {
+		return
+	}
+	previous, ok := live.Spec.Template.Annotations[certHashAnnotation]
+	if !ok || previous == tls.certHash {
+		return
+	}
+	log.Info("restarting to load a new certificate", zap.String("secret", tls.secretName))
+	c.recorder.Eventf(s, v1.EventTypeNormal, reasonCertRotated, "Restarting the server to load the new certificate in Secret %s", tls.secretName)
+}
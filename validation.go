@@ -0,0 +1,519 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	"go.uber.org/zap"
+	admission "k8s.io/api/admission/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metavalidation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Length of the random suffix the API server appends to generateName.
+const generatedNameSuffixLength = 5
+
+// validationRule checks a MySql being created, when old is nil, or updated,
+// and describes every problem it finds.
+type validationRule func(old *mysql.MySql, s *mysql.MySql) []string
+
+func (w *webhookServer) validationRules() []validationRule {
+	return []validationRule{
+		validateName,
+		w.validateImage,
+		w.validateStorage,
+		validateCredentials,
+		validateService,
+		w.validateTLS,
+		validateScheduling,
+		validateSecurity,
+		validatePodTemplate,
+		validateNetworkPolicy,
+	}
+}
+
+// checkMySql runs every validation rule against s.
+func (w *webhookServer) checkMySql(old *mysql.MySql, s *mysql.MySql) []string {
+	// The operator has to be able to remove its finalizer from whatever is
+	// being deleted.
+	if s.DeletionTimestamp != nil {
+		return nil
+	}
+	// Updates that leave the spec alone, such as the operator writing the
+	// status or its finalizer, go through even for instances created before
+	// a rule existed.
+	if old != nil && equality.Semantic.DeepEqual(old.Spec, s.Spec) {
+		return nil
+	}
+	var problems []string
+	for _, rule := range w.validationRules() {
+		problems = append(problems, rule(old, s)...)
+	}
+	return problems
+}
+
+// The name of a MySql is used for its Service, which has to be a DNS label,
+// and for the objects derived from it such as its claim.
+func validateName(old *mysql.MySql, s *mysql.MySql) []string {
+	if old != nil {
+		return nil
+	}
+	name := s.Name
+	if name == "" && s.GenerateName != "" {
+		name = s.GenerateName + strings.Repeat("x", generatedNameSuffixLength)
+	}
+
+	var problems []string
+	for _, msg := range validation.IsDNS1035Label(name) {
+		problems = append(problems, fmt.Sprintf("metadata.name: %s", msg))
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(getPvcName(name)) {
+		problems = append(problems, fmt.Sprintf("metadata.name: claim name %q is invalid: %s", getPvcName(name), msg))
+	}
+	return problems
+}
+
+// The image has to match one of the allowed patterns. Instances created
+// before the list changed keep their image.
+func (w *webhookServer) validateImage(old *mysql.MySql, s *mysql.MySql) []string {
+	if len(w.config.AllowedImages) == 0 {
+		return nil
+	}
+	image := w.defaults.image(s)
+	if old != nil && w.defaults.image(old) == image {
+		return nil
+	}
+	for _, pattern := range w.config.AllowedImages {
+		if ok, _ := path.Match(pattern, image); ok {
+			return nil
+		}
+	}
+	return []string{fmt.Sprintf("spec.image: %q is not allowed, it must match one of %s", image, strings.Join(w.config.AllowedImages, ", "))}
+}
+
+// The volume can grow but not shrink, and cannot be moved to another storage
+// class or zone.
+func (w *webhookServer) validateStorage(old *mysql.MySql, s *mysql.MySql) []string {
+	var problems []string
+	size, err := w.defaults.storage(s)
+	if err != nil {
+		return []string{fmt.Sprintf("spec.storage.size: %v", err)}
+	}
+	if size.Sign() <= 0 {
+		problems = append(problems, fmt.Sprintf("spec.storage.size: must be positive, got %s", size.String()))
+	}
+	if old == nil {
+		return problems
+	}
+
+	oldSize, err := w.defaults.storage(old)
+	if err == nil && size.Cmp(oldSize) < 0 {
+		problems = append(problems, fmt.Sprintf("spec.storage.size: cannot shrink from %s to %s", oldSize.String(), size.String()))
+	}
+	if s.Spec.Storage.StorageClassName != old.Spec.Storage.StorageClassName {
+		problems = append(problems, fmt.Sprintf("spec.storage.storageClassName: field is immutable, it was %q", old.Spec.Storage.StorageClassName))
+	}
+	if s.Spec.Storage.Zone != old.Spec.Storage.Zone {
+		problems = append(problems, fmt.Sprintf("spec.storage.zone: field is immutable, it was %q", old.Spec.Storage.Zone))
+	}
+	return problems
+}
+
+// The root password comes from exactly one place.
+func validateCredentials(old *mysql.MySql, s *mysql.MySql) []string {
+	creds := s.Spec.Credentials
+	ref := creds.RootPasswordSecretRef
+	switch {
+	case ref == nil && creds.RootPassword == "":
+		return []string{"spec.credentials: one of rootPasswordSecretRef and rootPassword is required"}
+	case ref != nil && creds.RootPassword != "":
+		return []string{"spec.credentials: only one of rootPasswordSecretRef and rootPassword may be set"}
+	case ref != nil && ref.Name == "":
+		return []string{"spec.credentials.rootPasswordSecretRef.name: must not be empty"}
+	}
+	return nil
+}
+
+// Ports need distinct names once there is more than one. Node ports and the
+// traffic policy only apply to NodePort and LoadBalancer Services, and source
+// ranges only to LoadBalancer ones.
+func validateService(old *mysql.MySql, s *mysql.MySql) []string {
+	spec := s.Spec.Service
+	svcType := serviceType(s)
+	var problems []string
+
+	names := map[string]bool{}
+	ports := map[int32]bool{}
+	for i, p := range spec.Ports {
+		field := fmt.Sprintf("spec.service.ports[%d]", i)
+		if p.Name == "" && len(spec.Ports) > 1 {
+			problems = append(problems, field+".name: required when there is more than one port")
+		}
+		if p.Name != "" {
+			for _, msg := range validation.IsValidPortName(p.Name) {
+				problems = append(problems, fmt.Sprintf("%s.name: %s", field, msg))
+			}
+			if names[p.Name] {
+				problems = append(problems, fmt.Sprintf("%s.name: duplicate name %q", field, p.Name))
+			}
+			names[p.Name] = true
+		}
+		for _, msg := range validation.IsValidPortNum(int(p.Port)) {
+			problems = append(problems, fmt.Sprintf("%s.port: %s", field, msg))
+		}
+		if ports[p.Port] {
+			problems = append(problems, fmt.Sprintf("%s.port: duplicate port %d", field, p.Port))
+		}
+		ports[p.Port] = true
+		if p.NodePort != 0 && svcType == v1.ServiceTypeClusterIP {
+			problems = append(problems, field+".nodePort: only allowed for NodePort and LoadBalancer Services")
+		}
+	}
+
+	if len(spec.LoadBalancerSourceRanges) > 0 && svcType != v1.ServiceTypeLoadBalancer {
+		problems = append(problems, "spec.service.loadBalancerSourceRanges: only allowed for LoadBalancer Services")
+	}
+	for i, cidr := range spec.LoadBalancerSourceRanges {
+		if _, _, err := net.ParseCIDR(strings.TrimSpace(cidr)); err != nil {
+			problems = append(problems, fmt.Sprintf("spec.service.loadBalancerSourceRanges[%d]: %q is not a CIDR", i, cidr))
+		}
+	}
+	if spec.ExternalTrafficPolicy != "" && svcType == v1.ServiceTypeClusterIP {
+		problems = append(problems, "spec.service.externalTrafficPolicy: only allowed for NodePort and LoadBalancer Services")
+	}
+	for k := range spec.Annotations {
+		for _, msg := range validation.IsQualifiedName(strings.ToLower(k)) {
+			problems = append(problems, fmt.Sprintf("spec.service.annotations: key %q is invalid: %s", k, msg))
+		}
+		if k == managedAnnotationsAnnotation {
+			problems = append(problems, fmt.Sprintf("spec.service.annotations: key %q is reserved for the operator", k))
+		}
+	}
+	return problems
+}
+
+// A user supplied certificate Secret must not be one the operator writes, and
+// secure transport can only be required when TLS is served by a server that
+// knows the option.
+func (w *webhookServer) validateTLS(old *mysql.MySql, s *mysql.MySql) []string {
+	spec := s.Spec.TLS
+	var problems []string
+	if spec.SecretName != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(spec.SecretName) {
+			problems = append(problems, fmt.Sprintf("spec.tls.secretName: %s", msg))
+		}
+		if spec.SecretName == getCASecretName(s.Name) {
+			problems = append(problems, fmt.Sprintf("spec.tls.secretName: %q is written by the operator", spec.SecretName))
+		}
+	}
+	if spec.RequireSecureTransport && !spec.Enabled {
+		problems = append(problems, "spec.tls.requireSecureTransport: requires spec.tls.enabled")
+	}
+	if image := w.defaults.image(s); spec.RequireSecureTransport && imageBefore(image, 5, 7) {
+		problems = append(problems, fmt.Sprintf("spec.tls.requireSecureTransport: needs MySQL 5.7 or later, %q refuses to start with it", image))
+	}
+	return problems
+}
+
+// Whether the tag of image names a MySQL release before major.minor. Tags that
+// do not start with a version, such as latest, are assumed to be new enough.
+func imageBefore(image string, major, minor int) bool {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return false
+	}
+	var gotMajor, gotMinor int
+	if n, _ := fmt.Sscanf(image[i+1:], "%d.%d", &gotMajor, &gotMinor); n < 2 {
+		return false
+	}
+	return gotMajor < major || gotMajor == major && gotMinor < minor
+}
+
+// The parts of spec.scheduling the API server would otherwise only reject once
+// the Deployment is written. Affinity is left to the API server.
+func validateScheduling(old *mysql.MySql, s *mysql.MySql) []string {
+	spec := s.Spec.Scheduling
+	var problems []string
+
+	for k, v := range spec.NodeSelector {
+		for _, msg := range validation.IsQualifiedName(k) {
+			problems = append(problems, fmt.Sprintf("spec.scheduling.nodeSelector: key %q is invalid: %s", k, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(v) {
+			problems = append(problems, fmt.Sprintf("spec.scheduling.nodeSelector[%s]: %s", k, msg))
+		}
+	}
+
+	for i, t := range spec.Tolerations {
+		field := fmt.Sprintf("spec.scheduling.tolerations[%d]", i)
+		switch t.Operator {
+		case v1.TolerationOpExists:
+			if t.Value != "" {
+				problems = append(problems, field+".value: must be empty when operator is Exists")
+			}
+		case "", v1.TolerationOpEqual:
+			if t.Key == "" {
+				problems = append(problems, field+".operator: must be Exists when key is empty")
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("%s.operator: %q is not Equal or Exists", field, t.Operator))
+		}
+		switch t.Effect {
+		case "", v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute:
+		default:
+			problems = append(problems, fmt.Sprintf("%s.effect: %q is not NoSchedule, PreferNoSchedule or NoExecute", field, t.Effect))
+		}
+		if t.TolerationSeconds != nil && t.Effect != v1.TaintEffectNoExecute {
+			problems = append(problems, field+".tolerationSeconds: only allowed when effect is NoExecute")
+		}
+	}
+
+	seen := map[string]bool{}
+	for i, c := range spec.TopologySpreadConstraints {
+		field := fmt.Sprintf("spec.scheduling.topologySpreadConstraints[%d]", i)
+		if c.MaxSkew <= 0 {
+			problems = append(problems, field+".maxSkew: must be positive")
+		}
+		if c.TopologyKey == "" {
+			problems = append(problems, field+".topologyKey: required")
+		}
+		if c.WhenUnsatisfiable != v1.DoNotSchedule && c.WhenUnsatisfiable != v1.ScheduleAnyway {
+			problems = append(problems, fmt.Sprintf("%s.whenUnsatisfiable: %q is not DoNotSchedule or ScheduleAnyway", field, c.WhenUnsatisfiable))
+		}
+		pair := fmt.Sprintf("%s/%s", c.TopologyKey, c.WhenUnsatisfiable)
+		if seen[pair] {
+			problems = append(problems, field+": duplicate topologyKey and whenUnsatisfiable")
+		}
+		seen[pair] = true
+	}
+
+	if spec.PriorityClassName != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(spec.PriorityClassName) {
+			problems = append(problems, fmt.Sprintf("spec.scheduling.priorityClassName: %s", msg))
+		}
+	}
+	if spec.RuntimeClassName != nil {
+		for _, msg := range validation.IsDNS1123Subdomain(*spec.RuntimeClassName) {
+			problems = append(problems, fmt.Sprintf("spec.scheduling.runtimeClassName: %s", msg))
+		}
+	}
+	return problems
+}
+
+// The seccomp profile has to be one the kubelet understands.
+func validateSecurity(old *mysql.MySql, s *mysql.MySql) []string {
+	profile := s.Spec.Security.SeccompProfile
+	switch {
+	case profile == "", profile == v1.SeccompProfileRuntimeDefault, profile == "docker/default", profile == "unconfined":
+		return nil
+	case strings.HasPrefix(profile, "localhost/") && len(profile) > len("localhost/"):
+		return nil
+	}
+	return []string{fmt.Sprintf("spec.security.seccompProfile: %q is not runtime/default, docker/default, unconfined or localhost/<path>", profile)}
+}
+
+// Additions to the pod must not clash with the operator's container, volumes,
+// mounts or environment, and the override must leave them in place.
+func validatePodTemplate(old *mysql.MySql, s *mysql.MySql) []string {
+	spec := s.Spec.PodTemplate
+	reserved := operatorVolumes(s)
+	var problems []string
+
+	containers := map[string]bool{mysqlContainerName: true}
+	checkContainers := func(field string, list []v1.Container) {
+		for i, c := range list {
+			path := fmt.Sprintf("spec.podTemplate.%s[%d]", field, i)
+			for _, msg := range validation.IsDNS1123Label(c.Name) {
+				problems = append(problems, fmt.Sprintf("%s.name: %s", path, msg))
+			}
+			if containers[c.Name] {
+				problems = append(problems, fmt.Sprintf("%s.name: %q is already used", path, c.Name))
+			}
+			containers[c.Name] = true
+			if c.Image == "" {
+				problems = append(problems, path+".image: required")
+			}
+		}
+	}
+	checkContainers("containers", spec.Containers)
+	checkContainers("initContainers", spec.InitContainers)
+
+	volumes := map[string]bool{}
+	for i, v := range spec.Volumes {
+		path := fmt.Sprintf("spec.podTemplate.volumes[%d]", i)
+		for _, msg := range validation.IsDNS1123Label(v.Name) {
+			problems = append(problems, fmt.Sprintf("%s.name: %s", path, msg))
+		}
+		if _, ok := reserved[v.Name]; ok || volumes[v.Name] {
+			problems = append(problems, fmt.Sprintf("%s.name: %q is already used", path, v.Name))
+		}
+		volumes[v.Name] = true
+	}
+
+	mountPaths := map[string]bool{}
+	for _, p := range reserved {
+		mountPaths[p] = true
+	}
+	for i, m := range spec.VolumeMounts {
+		path := fmt.Sprintf("spec.podTemplate.volumeMounts[%d]", i)
+		if _, ok := reserved[m.Name]; !ok && !volumes[m.Name] {
+			problems = append(problems, fmt.Sprintf("%s.name: no volume named %q", path, m.Name))
+		}
+		if mountPaths[m.MountPath] {
+			problems = append(problems, fmt.Sprintf("%s.mountPath: %q is already used", path, m.MountPath))
+		}
+		mountPaths[m.MountPath] = true
+	}
+
+	env := map[string]bool{rootPasswordEnv(s).Name: true}
+	for i, e := range spec.Env {
+		path := fmt.Sprintf("spec.podTemplate.env[%d]", i)
+		if e.Name == "" {
+			problems = append(problems, path+".name: required")
+		} else if env[e.Name] {
+			problems = append(problems, fmt.Sprintf("%s.name: %q is already set", path, e.Name))
+		}
+		env[e.Name] = true
+	}
+
+	if spec.Override != nil && len(spec.Override.Raw) > 0 {
+		skeleton := operatorSkeleton(s)
+		kept := volumeNames(skeleton.Spec.Volumes)
+		if err := strategicMerge(skeleton, spec.Override.Raw); err != nil {
+			problems = append(problems, fmt.Sprintf("spec.podTemplate.override: %v", err))
+		} else if err := checkOperatorParts(skeleton, kept); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return problems
+}
+
+// Every allowed source needs a selector, and the selectors have to be ones a
+// NetworkPolicy accepts.
+func validateNetworkPolicy(old *mysql.MySql, s *mysql.MySql) []string {
+	spec := s.Spec.NetworkPolicy
+	path := field.NewPath("spec", "networkPolicy")
+	problems := validatePeers(spec.AllowFrom, path.Child("allowFrom"))
+	return append(problems, validatePeers(spec.SidecarAllowFrom, path.Child("sidecarAllowFrom"))...)
+}
+
+func validatePeers(peers []mysql.NetworkPolicyPeer, path *field.Path) []string {
+	var problems []string
+	for i, peer := range peers {
+		path := path.Index(i)
+		if peer.NamespaceSelector == nil && peer.PodSelector == nil {
+			problems = append(problems, path.String()+": namespaceSelector or podSelector is required")
+		}
+		errs := metavalidation.ValidateLabelSelector(peer.NamespaceSelector, metavalidation.LabelSelectorValidationOptions{}, path.Child("namespaceSelector"))
+		errs = append(errs, metavalidation.ValidateLabelSelector(peer.PodSelector, metavalidation.LabelSelectorValidationOptions{}, path.Child("podSelector"))...)
+		for _, err := range errs {
+			problems = append(problems, err.Error())
+		}
+	}
+	return problems
+}
+
+// validateMySql refuses to create or update a MySql that breaks one of the
+// validation rules.
+func (w *webhookServer) validateMySql(req *admission.AdmissionRequest) *admission.AdmissionResponse {
+	if req.Resource.Resource != mysql.MySqlPlural {
+		return allow()
+	}
+
+	s := &mysql.MySql{}
+	if err := json.Unmarshal(req.Object.Raw, s); err != nil {
+		return deny(http.StatusBadRequest, "failed to decode MySql: %v", err)
+	}
+	var old *mysql.MySql
+	if req.Operation == admission.Update {
+		old = &mysql.MySql{}
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			return deny(http.StatusBadRequest, "failed to decode MySql: %v", err)
+		}
+	}
+
+	problems := w.checkMySql(old, s)
+	if len(problems) == 0 {
+		return allow()
+	}
+	log.Info("refused invalid mysql",
+		zap.String("namespace", req.Namespace),
+		zap.String("name", req.Name),
+		zap.String("operation", string(req.Operation)),
+		zap.Strings("problems", problems))
+	return deny(http.StatusUnprocessableEntity, "MySql %s/%s is invalid: %s", req.Namespace, req.Name, strings.Join(problems, "; "))
+}
+
+// patchOperation is a single JSON patch operation.
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// defaultsPatch fills in the fields s leaves empty. Writing the operator's
+// defaults into the object means a later change to them does not change
+// instances that already exist. The image is the exception: it is resolved
+// on every reconcile, because a persisted image would win over a spec.version
+// set later.
+func (w *webhookServer) defaultsPatch(s *mysql.MySql) []patchOperation {
+	var patch []patchOperation
+	if s.Spec.DeletionPolicy == "" {
+		patch = append(patch, patchOperation{Op: "add", Path: "/spec/deletionPolicy", Value: mysql.DeletionPolicyDelete})
+	}
+	if s.Spec.Storage == (mysql.StorageSpec{}) {
+		patch = append(patch, patchOperation{Op: "add", Path: "/spec/storage", Value: map[string]string{"size": w.defaults.Storage}})
+	} else if s.Spec.Storage.Size == nil {
+		patch = append(patch, patchOperation{Op: "add", Path: "/spec/storage/size", Value: w.defaults.Storage})
+	}
+	return patch
+}
+
+// defaultMySql fills in defaults on MySql objects as they are created.
+func (w *webhookServer) defaultMySql(req *admission.AdmissionRequest) *admission.AdmissionResponse {
+	if req.Resource.Resource != mysql.MySqlPlural || req.Operation != admission.Create {
+		return allow()
+	}
+
+	s := &mysql.MySql{}
+	if err := json.Unmarshal(req.Object.Raw, s); err != nil {
+		return deny(http.StatusBadRequest, "failed to decode MySql: %v", err)
+	}
+	patch := w.defaultsPatch(s)
+	if len(patch) == 0 {
+		return allow()
+	}
+	raw, err := json.Marshal(patch)
+	if err != nil {
+		return deny(http.StatusInternalServerError, "failed to encode defaults: %v", err)
+	}
+	patchType := admission.PatchTypeJSONPatch
+	return &admission.AdmissionResponse{Allowed: true, Patch: raw, PatchType: &patchType}
+}
@@ -0,0 +1,931 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	admission "k8s.io/api/admission/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func testWebhookServer(allowedImages ...string) *webhookServer {
+	return &webhookServer{
+		config:   webhookConfig{AllowedImages: allowedImages},
+		defaults: instanceDefaults{Image: "mysql:5.6", Storage: "20G"},
+	}
+}
+
+// A MySql named name, modified by each of opts.
+func testMySql(name string, opts ...func(*mysql.MySql)) *mysql.MySql {
+	s := &mysql.MySql{
+		ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: mysql.MySqlSpec{
+			Credentials: mysql.CredentialsSpec{RootPassword: "password"},
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func withImage(image string) func(*mysql.MySql) {
+	return func(s *mysql.MySql) { s.Spec.Image = image }
+}
+
+func withVersion(version string) func(*mysql.MySql) {
+	return func(s *mysql.MySql) { s.Spec.Version = version }
+}
+
+func withSecretRef(name string, key string) func(*mysql.MySql) {
+	return func(s *mysql.MySql) {
+		s.Spec.Credentials.RootPassword = ""
+		s.Spec.Credentials.RootPasswordSecretRef = &v1.SecretKeySelector{
+			LocalObjectReference: v1.LocalObjectReference{Name: name},
+			Key:                  key,
+		}
+	}
+}
+
+func withSize(size string) func(*mysql.MySql) {
+	return func(s *mysql.MySql) {
+		q := resource.MustParse(size)
+		s.Spec.Storage.Size = &q
+	}
+}
+
+func withStorageClass(class string) func(*mysql.MySql) {
+	return func(s *mysql.MySql) { s.Spec.Storage.StorageClassName = class }
+}
+
+func withZone(zone string) func(*mysql.MySql) {
+	return func(s *mysql.MySql) { s.Spec.Storage.Zone = zone }
+}
+
+// Check problems holds exactly one problem per entry of want, each containing
+// that entry.
+func checkProblems(t *testing.T, problems []string, want []string) {
+	t.Helper()
+	if len(problems) != len(want) {
+		t.Fatalf("got problems %q, want %d matching %q", problems, len(want), want)
+	}
+	for i := range want {
+		if !strings.Contains(problems[i], want[i]) {
+			t.Errorf("problem %q does not contain %q", problems[i], want[i])
+		}
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name string
+		old  *mysql.MySql
+		s    *mysql.MySql
+		want []string
+	}{
+		{
+			name: "valid",
+			s:    testMySql("mysql"),
+		},
+		{
+			name: "longest allowed",
+			s:    testMySql(strings.Repeat("a", 63)),
+		},
+		{
+			name: "too long for a service",
+			s:    testMySql(strings.Repeat("a", 64)),
+			want: []string{"metadata.name"},
+		},
+		{
+			name: "starts with a digit",
+			s:    testMySql("1mysql"),
+			want: []string{"metadata.name"},
+		},
+		{
+			name: "generated name leaves room for the suffix",
+			s: testMySql("", func(s *mysql.MySql) {
+				s.GenerateName = strings.Repeat("a", 58)
+			}),
+		},
+		{
+			name: "generated name too long once suffixed",
+			s: testMySql("", func(s *mysql.MySql) {
+				s.GenerateName = strings.Repeat("a", 59)
+			}),
+			want: []string{"metadata.name"},
+		},
+		{
+			name: "only checked on create",
+			old:  testMySql(strings.Repeat("a", 64)),
+			s:    testMySql(strings.Repeat("a", 64)),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkProblems(t, validateName(test.old, test.s), test.want)
+		})
+	}
+}
+
+func TestValidateImage(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		old     *mysql.MySql
+		s       *mysql.MySql
+		want    []string
+	}{
+		{
+			name: "any image without an allowlist",
+			s:    testMySql("mysql", withImage("evil/miner:latest")),
+		},
+		{
+			name:    "matches a pattern",
+			allowed: []string{"mysql:*", "registry.example.com/mysql:*"},
+			s:       testMySql("mysql", withImage("registry.example.com/mysql:8.0")),
+		},
+		{
+			name:    "matches no pattern",
+			allowed: []string{"mysql:*"},
+			s:       testMySql("mysql", withImage("evil/miner:latest")),
+			want:    []string{"spec.image"},
+		},
+		{
+			name:    "wildcard does not cross a slash",
+			allowed: []string{"mysql*"},
+			s:       testMySql("mysql", withImage("mysql/../evil:latest")),
+			want:    []string{"spec.image"},
+		},
+		{
+			name:    "default image is checked",
+			allowed: []string{"mysql:8.*"},
+			s:       testMySql("mysql"),
+			want:    []string{"spec.image"},
+		},
+		{
+			name:    "image picked by version is checked",
+			allowed: []string{"mysql:8.*"},
+			s:       testMySql("mysql", withVersion("5.7")),
+			want:    []string{"spec.image: \"mysql:5.7\""},
+		},
+		{
+			name:    "version allowed",
+			allowed: []string{"mysql:8.*"},
+			s:       testMySql("mysql", withVersion("8.0.17")),
+		},
+		{
+			name:    "unchanged image is kept",
+			allowed: []string{"mysql:8.*"},
+			old:     testMySql("mysql", withImage("mysql:5.6")),
+			s:       testMySql("mysql", withImage("mysql:5.6")),
+		},
+		{
+			name:    "changed image is checked",
+			allowed: []string{"mysql:8.*"},
+			old:     testMySql("mysql", withImage("mysql:8.0")),
+			s:       testMySql("mysql", withImage("mysql:5.6")),
+			want:    []string{"spec.image"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := testWebhookServer(test.allowed...)
+			checkProblems(t, w.validateImage(test.old, test.s), test.want)
+		})
+	}
+}
+
+func TestValidateStorage(t *testing.T) {
+	tests := []struct {
+		name string
+		old  *mysql.MySql
+		s    *mysql.MySql
+		want []string
+	}{
+		{
+			name: "defaults",
+			s:    testMySql("mysql"),
+		},
+		{
+			name: "zero size",
+			s:    testMySql("mysql", withSize("0")),
+			want: []string{"spec.storage.size: must be positive"},
+		},
+		{
+			name: "grow",
+			old:  testMySql("mysql", withSize("10Gi")),
+			s:    testMySql("mysql", withSize("20Gi")),
+		},
+		{
+			name: "same size written differently",
+			old:  testMySql("mysql", withSize("1Gi")),
+			s:    testMySql("mysql", withSize("1024Mi")),
+		},
+		{
+			name: "shrink",
+			old:  testMySql("mysql", withSize("20Gi")),
+			s:    testMySql("mysql", withSize("10Gi")),
+			want: []string{"spec.storage.size: cannot shrink"},
+		},
+		{
+			name: "shrink below the default it was created with",
+			old:  testMySql("mysql"),
+			s:    testMySql("mysql", withSize("10G")),
+			want: []string{"spec.storage.size: cannot shrink"},
+		},
+		{
+			name: "change storage class",
+			old:  testMySql("mysql", withStorageClass("standard")),
+			s:    testMySql("mysql", withStorageClass("fast")),
+			want: []string{"spec.storage.storageClassName: field is immutable"},
+		},
+		{
+			name: "set storage class",
+			old:  testMySql("mysql"),
+			s:    testMySql("mysql", withStorageClass("fast")),
+			want: []string{"spec.storage.storageClassName: field is immutable"},
+		},
+		{
+			name: "change zone",
+			old:  testMySql("mysql", withZone("us-east1-b")),
+			s:    testMySql("mysql", withZone("us-east1-c")),
+			want: []string{"spec.storage.zone: field is immutable"},
+		},
+		{
+			name: "everything at once",
+			old:  testMySql("mysql", withSize("20Gi"), withStorageClass("standard"), withZone("us-east1-b")),
+			s:    testMySql("mysql", withSize("10Gi"), withStorageClass("fast"), withZone("us-east1-c")),
+			want: []string{"spec.storage.size", "spec.storage.storageClassName", "spec.storage.zone"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := testWebhookServer()
+			checkProblems(t, w.validateStorage(test.old, test.s), test.want)
+		})
+	}
+}
+
+func TestValidateCredentials(t *testing.T) {
+	tests := []struct {
+		name string
+		s    *mysql.MySql
+		want []string
+	}{
+		{
+			name: "plain text password",
+			s:    testMySql("mysql"),
+		},
+		{
+			name: "secret reference",
+			s:    testMySql("mysql", withSecretRef("mysql-root", "password")),
+		},
+		{
+			name: "neither",
+			s: testMySql("mysql", func(s *mysql.MySql) {
+				s.Spec.Credentials = mysql.CredentialsSpec{}
+			}),
+			want: []string{"is required"},
+		},
+		{
+			name: "both",
+			s: testMySql("mysql", withSecretRef("mysql-root", "password"), func(s *mysql.MySql) {
+				s.Spec.Credentials.RootPassword = "password"
+			}),
+			want: []string{"only one of"},
+		},
+		{
+			name: "secret without a name",
+			s:    testMySql("mysql", withSecretRef("", "password")),
+			want: []string{"rootPasswordSecretRef.name"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkProblems(t, validateCredentials(nil, test.s), test.want)
+		})
+	}
+}
+
+func withService(service mysql.ServiceSpec) func(*mysql.MySql) {
+	return func(s *mysql.MySql) { s.Spec.Service = service }
+}
+
+func TestValidateService(t *testing.T) {
+	tests := []struct {
+		name string
+		s    *mysql.MySql
+		want []string
+	}{
+		{
+			name: "default",
+			s:    testMySql("mysql"),
+		},
+		{
+			name: "load balancer",
+			s: testMySql("mysql", withService(mysql.ServiceSpec{
+				Type:                     v1.ServiceTypeLoadBalancer,
+				Ports:                    []mysql.ServicePort{{Name: "mysql", Port: 3306}, {Name: "legacy", Port: 3307, NodePort: 30307}},
+				Annotations:              map[string]string{"service.beta.kubernetes.io/aws-load-balancer-internal": "true"},
+				LoadBalancerSourceRanges: []string{"10.0.0.0/8"},
+				ExternalTrafficPolicy:    v1.ServiceExternalTrafficPolicyTypeLocal,
+			})),
+		},
+		{
+			name: "unnamed ports",
+			s: testMySql("mysql", withService(mysql.ServiceSpec{
+				Ports: []mysql.ServicePort{{Port: 3306}, {Port: 3307}},
+			})),
+			want: []string{"ports[0].name: required", "ports[1].name: required"},
+		},
+		{
+			name: "duplicate ports",
+			s: testMySql("mysql", withService(mysql.ServiceSpec{
+				Ports: []mysql.ServicePort{{Name: "a", Port: 3306}, {Name: "a", Port: 3306}},
+			})),
+			want: []string{"ports[1].name: duplicate", "ports[1].port: duplicate"},
+		},
+		{
+			name: "port out of range",
+			s: testMySql("mysql", withService(mysql.ServiceSpec{
+				Ports: []mysql.ServicePort{{Port: 70000}},
+			})),
+			want: []string{"ports[0].port"},
+		},
+		{
+			name: "node port on a cluster IP service",
+			s: testMySql("mysql", withService(mysql.ServiceSpec{
+				Ports: []mysql.ServicePort{{Port: 3306, NodePort: 30306}},
+			})),
+			want: []string{"ports[0].nodePort"},
+		},
+		{
+			name: "source ranges on a node port service",
+			s: testMySql("mysql", withService(mysql.ServiceSpec{
+				Type:                     v1.ServiceTypeNodePort,
+				LoadBalancerSourceRanges: []string{"10.0.0.0/8"},
+			})),
+			want: []string{"loadBalancerSourceRanges: only allowed"},
+		},
+		{
+			name: "bad source range",
+			s: testMySql("mysql", withService(mysql.ServiceSpec{
+				Type:                     v1.ServiceTypeLoadBalancer,
+				LoadBalancerSourceRanges: []string{"10.0.0.0"},
+			})),
+			want: []string{"loadBalancerSourceRanges[0]"},
+		},
+		{
+			name: "traffic policy on a cluster IP service",
+			s: testMySql("mysql", withService(mysql.ServiceSpec{
+				ExternalTrafficPolicy: v1.ServiceExternalTrafficPolicyTypeLocal,
+			})),
+			want: []string{"externalTrafficPolicy"},
+		},
+		{
+			name: "reserved annotation",
+			s: testMySql("mysql", withService(mysql.ServiceSpec{
+				Annotations: map[string]string{managedAnnotationsAnnotation: "a"},
+			})),
+			want: []string{"reserved"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkProblems(t, validateService(nil, test.s), test.want)
+		})
+	}
+}
+
+func TestCheckMySql(t *testing.T) {
+	deleting := func(s *mysql.MySql) {
+		now := meta_v1.Now()
+		s.DeletionTimestamp = &now
+	}
+	noCredentials := func(s *mysql.MySql) {
+		s.Spec.Credentials = mysql.CredentialsSpec{}
+	}
+	tests := []struct {
+		name string
+		old  *mysql.MySql
+		s    *mysql.MySql
+		want []string
+	}{
+		{
+			name: "valid",
+			s:    testMySql("mysql", withImage("mysql:5.7")),
+		},
+		{
+			name: "problems from every rule",
+			s: testMySql(strings.Repeat("a", 64), withImage("evil/miner:latest"), withSize("0"), func(s *mysql.MySql) {
+				s.Spec.Credentials = mysql.CredentialsSpec{}
+			}),
+			want: []string{"metadata.name", "spec.image", "spec.storage.size", "spec.credentials"},
+		},
+		{
+			name: "deleted instances can still lose their finalizer",
+			old:  testMySql("mysql", withSize("20Gi"), deleting),
+			s:    testMySql("mysql", withSize("10Gi"), deleting),
+		},
+		{
+			name: "status and finalizer updates leave an unchanged spec alone",
+			old:  testMySql("mysql", noCredentials),
+			s: testMySql("mysql", noCredentials, withFinalizer, func(s *mysql.MySql) {
+				s.Status.Phase = mysql.MySqlPhaseRunning
+			}),
+		},
+		{
+			name: "spec changes are checked",
+			old:  testMySql("mysql", noCredentials),
+			s:    testMySql("mysql", noCredentials, withSize("30Gi")),
+			want: []string{"spec.credentials"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := testWebhookServer("mysql:*")
+			checkProblems(t, w.checkMySql(test.old, test.s), test.want)
+		})
+	}
+}
+
+func TestDefaultsPatch(t *testing.T) {
+	tests := []struct {
+		name string
+		s    *mysql.MySql
+		want []patchOperation
+	}{
+		{
+			name: "everything defaulted",
+			s:    testMySql("mysql"),
+			want: []patchOperation{
+				{Op: "add", Path: "/spec/deletionPolicy", Value: mysql.DeletionPolicyDelete},
+				{Op: "add", Path: "/spec/storage", Value: map[string]string{"size": "20G"}},
+			},
+		},
+		{
+			name: "storage without a size",
+			s: testMySql("mysql", withImage("mysql:8.0"), withStorageClass("fast"), func(s *mysql.MySql) {
+				s.Spec.DeletionPolicy = mysql.DeletionPolicyRetain
+			}),
+			want: []patchOperation{
+				{Op: "add", Path: "/spec/storage/size", Value: "20G"},
+			},
+		},
+		{
+			name: "version picks the image",
+			s:    testMySql("mysql", withVersion("8.0"), withSize("5Gi")),
+			want: []patchOperation{
+				{Op: "add", Path: "/spec/deletionPolicy", Value: mysql.DeletionPolicyDelete},
+			},
+		},
+		{
+			name: "nothing to default",
+			s: testMySql("mysql", withImage("mysql:8.0"), withSize("5Gi"), func(s *mysql.MySql) {
+				s.Spec.DeletionPolicy = mysql.DeletionPolicySnapshot
+			}),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, _ := json.Marshal(testWebhookServer().defaultsPatch(test.s))
+			want, _ := json.Marshal(test.want)
+			if string(got) != string(want) {
+				t.Errorf("got patch %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+// A defaulted MySql keeps following the operator's default image until it
+// sets spec.version.
+func TestDefaultedImage(t *testing.T) {
+	w := testWebhookServer()
+	s := testMySql("mysql")
+	raw, _ := json.Marshal(w.defaultsPatch(s))
+	patch, err := jsonpatch.DecodePatch(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, _ := json.Marshal(s)
+	if doc, err = patch.Apply(doc); err != nil {
+		t.Fatal(err)
+	}
+	defaulted := &mysql.MySql{}
+	if err := json.Unmarshal(doc, defaulted); err != nil {
+		t.Fatal(err)
+	}
+
+	if image := w.defaults.image(defaulted); image != "mysql:5.6" {
+		t.Errorf("got image %q, want the default mysql:5.6", image)
+	}
+	defaulted.Spec.Version = "8.0"
+	if image := w.defaults.image(defaulted); image != "mysql:8.0" {
+		t.Errorf("got image %q after setting spec.version, want mysql:8.0", image)
+	}
+}
+
+func TestValidateMySqlResponse(t *testing.T) {
+	raw := func(s *mysql.MySql) runtime.RawExtension {
+		data, _ := json.Marshal(s)
+		return runtime.RawExtension{Raw: data}
+	}
+	tests := []struct {
+		name    string
+		req     *admission.AdmissionRequest
+		allowed bool
+		code    int32
+	}{
+		{
+			name: "valid create",
+			req: &admission.AdmissionRequest{
+				Operation: admission.Create,
+				Resource:  meta_v1.GroupVersionResource{Resource: mysql.MySqlPlural},
+				Object:    raw(testMySql("mysql")),
+			},
+			allowed: true,
+		},
+		{
+			name: "shrinking update",
+			req: &admission.AdmissionRequest{
+				Operation: admission.Update,
+				Resource:  meta_v1.GroupVersionResource{Resource: mysql.MySqlPlural},
+				Object:    raw(testMySql("mysql", withSize("1Gi"))),
+				OldObject: raw(testMySql("mysql", withSize("2Gi"))),
+			},
+			allowed: false,
+			code:    422,
+		},
+		{
+			name: "undecodable object",
+			req: &admission.AdmissionRequest{
+				Operation: admission.Create,
+				Resource:  meta_v1.GroupVersionResource{Resource: mysql.MySqlPlural},
+				Object:    runtime.RawExtension{Raw: []byte("{")},
+			},
+			allowed: false,
+			code:    400,
+		},
+		{
+			name: "other resources are ignored",
+			req: &admission.AdmissionRequest{
+				Operation: admission.Create,
+				Resource:  meta_v1.GroupVersionResource{Resource: "pods"},
+				Object:    runtime.RawExtension{Raw: []byte("{")},
+			},
+			allowed: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := testWebhookServer().validateMySql(test.req)
+			if resp.Allowed != test.allowed {
+				t.Fatalf("got allowed %t, want %t: %+v", resp.Allowed, test.allowed, resp.Result)
+			}
+			if !test.allowed && resp.Result.Code != test.code {
+				t.Errorf("got code %d, want %d", resp.Result.Code, test.code)
+			}
+		})
+	}
+}
+
+func withTLS(tls mysql.TLSSpec) func(*mysql.MySql) {
+	return func(s *mysql.MySql) { s.Spec.TLS = tls }
+}
+
+func TestValidateTLS(t *testing.T) {
+	tests := []struct {
+		name string
+		s    *mysql.MySql
+		want []string
+	}{
+		{
+			name: "disabled",
+			s:    testMySql("mysql"),
+		},
+		{
+			name: "generated certificates",
+			s:    testMySql("mysql", withVersion("8.0"), withTLS(mysql.TLSSpec{Enabled: true, RequireSecureTransport: true})),
+		},
+		{
+			name: "user supplied certificates",
+			s:    testMySql("mysql", withTLS(mysql.TLSSpec{Enabled: true, SecretName: "mysql-server-cert"})),
+		},
+		{
+			name: "invalid secret name",
+			s:    testMySql("mysql", withTLS(mysql.TLSSpec{Enabled: true, SecretName: "Server_Cert"})),
+			want: []string{"spec.tls.secretName"},
+		},
+		{
+			name: "published ca secret",
+			s:    testMySql("mysql", withTLS(mysql.TLSSpec{Enabled: true, SecretName: "mysql-ca"})),
+			want: []string{"written by the operator"},
+		},
+		{
+			name: "secure transport without tls",
+			s:    testMySql("mysql", withVersion("8.0"), withTLS(mysql.TLSSpec{RequireSecureTransport: true})),
+			want: []string{"requireSecureTransport: requires spec.tls.enabled"},
+		},
+		{
+			name: "secure transport on the default 5.6 image",
+			s:    testMySql("mysql", withTLS(mysql.TLSSpec{Enabled: true, RequireSecureTransport: true})),
+			want: []string{"needs MySQL 5.7"},
+		},
+		{
+			name: "secure transport on 5.6",
+			s:    testMySql("mysql", withImage("registry.local:5000/mysql:5.6.51"), withTLS(mysql.TLSSpec{Enabled: true, RequireSecureTransport: true})),
+			want: []string{"needs MySQL 5.7"},
+		},
+		{
+			name: "secure transport on 5.7",
+			s:    testMySql("mysql", withVersion("5.7"), withTLS(mysql.TLSSpec{Enabled: true, RequireSecureTransport: true})),
+		},
+		{
+			name: "secure transport on an untagged image",
+			s:    testMySql("mysql", withImage("registry.local:5000/mysql"), withTLS(mysql.TLSSpec{Enabled: true, RequireSecureTransport: true})),
+		},
+		{
+			name: "tls without secure transport on 5.6",
+			s:    testMySql("mysql", withTLS(mysql.TLSSpec{Enabled: true})),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkProblems(t, testWebhookServer().validateTLS(nil, test.s), test.want)
+		})
+	}
+}
+
+func withScheduling(scheduling mysql.SchedulingSpec) func(*mysql.MySql) {
+	return func(s *mysql.MySql) { s.Spec.Scheduling = scheduling }
+}
+
+func TestValidateScheduling(t *testing.T) {
+	seconds := int64(300)
+	badRuntimeClass := "gVisor"
+	tests := []struct {
+		name string
+		s    *mysql.MySql
+		want []string
+	}{
+		{
+			name: "default",
+			s:    testMySql("mysql"),
+		},
+		{
+			name: "every field",
+			s: testMySql("mysql", withScheduling(mysql.SchedulingSpec{
+				NodeSelector: map[string]string{"cloud.google.com/gke-nodepool": "databases"},
+				Tolerations: []v1.Toleration{
+					{Key: "dedicated", Value: "mysql", Effect: v1.TaintEffectNoSchedule},
+					{Key: "node.kubernetes.io/unreachable", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoExecute, TolerationSeconds: &seconds},
+				},
+				TopologySpreadConstraints: []v1.TopologySpreadConstraint{
+					{MaxSkew: 1, TopologyKey: v1.LabelZoneFailureDomain, WhenUnsatisfiable: v1.ScheduleAnyway},
+				},
+				PriorityClassName: "databases",
+			})),
+		},
+		{
+			name: "bad node selector",
+			s: testMySql("mysql", withScheduling(mysql.SchedulingSpec{
+				NodeSelector: map[string]string{"-pool": "a b"},
+			})),
+			want: []string{"nodeSelector: key", "nodeSelector[-pool]"},
+		},
+		{
+			name: "bad tolerations",
+			s: testMySql("mysql", withScheduling(mysql.SchedulingSpec{
+				Tolerations: []v1.Toleration{
+					{Operator: v1.TolerationOpExists, Value: "mysql"},
+					{Value: "mysql"},
+					{Key: "dedicated", Operator: "In", Effect: "Evict"},
+					{Key: "dedicated", Effect: v1.TaintEffectNoSchedule, TolerationSeconds: &seconds},
+				},
+			})),
+			want: []string{
+				"tolerations[0].value",
+				"tolerations[1].operator",
+				"tolerations[2].operator", "tolerations[2].effect",
+				"tolerations[3].tolerationSeconds",
+			},
+		},
+		{
+			name: "bad spread constraints",
+			s: testMySql("mysql", withScheduling(mysql.SchedulingSpec{
+				TopologySpreadConstraints: []v1.TopologySpreadConstraint{
+					{WhenUnsatisfiable: v1.DoNotSchedule},
+					{MaxSkew: 1, TopologyKey: v1.LabelHostname, WhenUnsatisfiable: "Sometimes"},
+					{MaxSkew: 1, TopologyKey: v1.LabelHostname, WhenUnsatisfiable: "Sometimes"},
+				},
+			})),
+			want: []string{
+				"topologySpreadConstraints[0].maxSkew", "topologySpreadConstraints[0].topologyKey",
+				"topologySpreadConstraints[1].whenUnsatisfiable",
+				"topologySpreadConstraints[2].whenUnsatisfiable", "topologySpreadConstraints[2]: duplicate",
+			},
+		},
+		{
+			name: "bad class names",
+			s: testMySql("mysql", withScheduling(mysql.SchedulingSpec{
+				PriorityClassName: "High_Priority",
+				RuntimeClassName:  &badRuntimeClass,
+			})),
+			want: []string{"priorityClassName", "runtimeClassName"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkProblems(t, validateScheduling(nil, test.s), test.want)
+		})
+	}
+}
+
+func TestValidateSecurity(t *testing.T) {
+	tests := []struct {
+		profile string
+		want    []string
+	}{
+		{profile: ""},
+		{profile: "runtime/default"},
+		{profile: "unconfined"},
+		{profile: "localhost/profiles/mysql.json"},
+		{profile: "localhost/", want: []string{"spec.security.seccompProfile"}},
+		{profile: "default", want: []string{"spec.security.seccompProfile"}},
+	}
+	for _, test := range tests {
+		t.Run(test.profile, func(t *testing.T) {
+			s := testMySql("mysql", func(s *mysql.MySql) { s.Spec.Security.SeccompProfile = test.profile })
+			checkProblems(t, validateSecurity(nil, s), test.want)
+		})
+	}
+}
+
+func withPodTemplate(podTemplate mysql.PodTemplateSpec) func(*mysql.MySql) {
+	return func(s *mysql.MySql) { s.Spec.PodTemplate = podTemplate }
+}
+
+func TestValidatePodTemplate(t *testing.T) {
+	override := func(patch string) *runtime.RawExtension {
+		return &runtime.RawExtension{Raw: []byte(patch)}
+	}
+	tests := []struct {
+		name string
+		s    *mysql.MySql
+		want []string
+	}{
+		{
+			name: "default",
+			s:    testMySql("mysql"),
+		},
+		{
+			name: "sidecar with a shared volume",
+			s: testMySql("db", withPodTemplate(mysql.PodTemplateSpec{
+				Containers: []v1.Container{{
+					Name:         "log-shipper",
+					Image:        "fluent/fluent-bit:1.3",
+					VolumeMounts: []v1.VolumeMount{{Name: "logs", MountPath: "/logs"}},
+				}},
+				InitContainers: []v1.Container{{Name: "init-config", Image: "busybox"}},
+				Volumes:        []v1.Volume{{Name: "logs", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}},
+				VolumeMounts:   []v1.VolumeMount{{Name: "logs", MountPath: "/var/log/mysql"}},
+				Env:            []v1.EnvVar{{Name: "TZ", Value: "UTC"}},
+				Override:       override(`{"spec":{"terminationGracePeriodSeconds":60,"containers":[{"name":"mysql-ctr","imagePullPolicy":"Always"}]}}`),
+			})),
+		},
+		{
+			name: "clashing names",
+			s: testMySql("db", withPodTemplate(mysql.PodTemplateSpec{
+				Containers:     []v1.Container{{Name: "mysql-ctr", Image: "busybox"}, {Name: "sidecar"}},
+				InitContainers: []v1.Container{{Name: "sidecar", Image: "busybox"}},
+				Volumes:        []v1.Volume{{Name: "tmp"}, {Name: "extra"}, {Name: "extra"}},
+			})),
+			want: []string{
+				"containers[0].name", "containers[1].image",
+				"initContainers[0].name",
+				"volumes[0].name", "volumes[2].name",
+			},
+		},
+		{
+			name: "clashing mounts and env",
+			s: testMySql("db", withPodTemplate(mysql.PodTemplateSpec{
+				VolumeMounts: []v1.VolumeMount{{Name: "missing", MountPath: "/extra"}, {Name: "tmp", MountPath: "/var/lib/mysql"}},
+				Env:          []v1.EnvVar{{Name: "MYSQL_ROOT_PASSWORD", Value: "x"}, {Value: "y"}},
+			})),
+			want: []string{"volumeMounts[0].name", "volumeMounts[1].mountPath", "env[0].name", "env[1].name"},
+		},
+		{
+			name: "override removes the mysql container",
+			s: testMySql("db", withPodTemplate(mysql.PodTemplateSpec{
+				Override: override(`{"spec":{"containers":[{"name":"mysql-ctr","$patch":"delete"}]}}`),
+			})),
+			want: []string{"keep the mysql-ctr container"},
+		},
+		{
+			name: "override removes a volume",
+			s: testMySql("db", withPodTemplate(mysql.PodTemplateSpec{
+				Override: override(`{"spec":{"volumes":[{"name":"tmp","$patch":"delete"}]}}`),
+			})),
+			want: []string{"keep the tmp volume"},
+		},
+		{
+			name: "override is not an object",
+			s: testMySql("db", withPodTemplate(mysql.PodTemplateSpec{
+				Override: override(`[]`),
+			})),
+			want: []string{"spec.podTemplate.override"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkProblems(t, validatePodTemplate(nil, test.s), test.want)
+		})
+	}
+}
+
+func withNetworkPolicy(peers ...mysql.NetworkPolicyPeer) func(*mysql.MySql) {
+	return func(s *mysql.MySql) { s.Spec.NetworkPolicy.AllowFrom = peers }
+}
+
+func withSidecarNetworkPolicy(peers ...mysql.NetworkPolicyPeer) func(*mysql.MySql) {
+	return func(s *mysql.MySql) { s.Spec.NetworkPolicy.SidecarAllowFrom = peers }
+}
+
+func TestValidateNetworkPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		s    *mysql.MySql
+		want []string
+	}{
+		{
+			name: "default",
+			s:    testMySql("mysql"),
+		},
+		{
+			name: "every kind of peer",
+			s: testMySql("mysql", withNetworkPolicy(
+				mysql.NetworkPolicyPeer{PodSelector: &meta_v1.LabelSelector{MatchLabels: map[string]string{"app": "shop"}}},
+				mysql.NetworkPolicyPeer{NamespaceSelector: &meta_v1.LabelSelector{}},
+				mysql.NetworkPolicyPeer{
+					NamespaceSelector: &meta_v1.LabelSelector{MatchLabels: map[string]string{"team": "web"}},
+					PodSelector: &meta_v1.LabelSelector{MatchExpressions: []meta_v1.LabelSelectorRequirement{
+						{Key: "app", Operator: meta_v1.LabelSelectorOpIn, Values: []string{"frontend"}},
+					}},
+				},
+			)),
+		},
+		{
+			name: "peer without selectors",
+			s:    testMySql("mysql", withNetworkPolicy(mysql.NetworkPolicyPeer{})),
+			want: []string{"spec.networkPolicy.allowFrom[0]: namespaceSelector or podSelector is required"},
+		},
+		{
+			name: "sidecar peer without selectors",
+			s:    testMySql("mysql", withSidecarNetworkPolicy(mysql.NetworkPolicyPeer{})),
+			want: []string{"spec.networkPolicy.sidecarAllowFrom[0]: namespaceSelector or podSelector is required"},
+		},
+		{
+			name: "bad selectors",
+			s: testMySql("mysql", withNetworkPolicy(
+				mysql.NetworkPolicyPeer{NamespaceSelector: &meta_v1.LabelSelector{MatchLabels: map[string]string{"team": "a b"}}},
+				mysql.NetworkPolicyPeer{PodSelector: &meta_v1.LabelSelector{MatchExpressions: []meta_v1.LabelSelectorRequirement{
+					{Key: "app", Operator: meta_v1.LabelSelectorOpIn},
+					{Key: "-app", Operator: "Matches"},
+				}}},
+			)),
+			want: []string{
+				"allowFrom[0].namespaceSelector.matchLabels",
+				"allowFrom[1].podSelector.matchExpressions[0].values",
+				"allowFrom[1].podSelector.matchExpressions[1].operator",
+				"allowFrom[1].podSelector.matchExpressions[1].key",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkProblems(t, validateNetworkPolicy(nil, test.s), test.want)
+		})
+	}
+}
@@ -0,0 +1,452 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tonya11en/mysql-operator/pkg/apis/myproject"
+	mysql "github.com/tonya11en/mysql-operator/pkg/apis/myproject/v1beta1"
+	mysqlclient "github.com/tonya11en/mysql-operator/pkg/client/clientset/versioned/typed/myproject/v1beta1"
+	"go.uber.org/zap"
+	admission "k8s.io/api/admission/v1"
+	admissionregistration "k8s.io/api/admissionregistration/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+)
+
+// Name of the webhook configurations the operator registers.
+const webhookConfigName = "mysql-operator"
+
+// Paths the webhooks are served on.
+const (
+	validateDeletePath = "/validate-delete"
+	validateMySqlPath  = "/validate-mysql"
+	mutateMySqlPath    = "/mutate-mysql"
+)
+
+// Versions of AdmissionReview and ConversionReview the webhooks understand.
+// The two versions of each have the same shape.
+var reviewVersions = []string{"v1", "v1beta1"}
+
+// webhookConfig controls the admission webhook server.
+type webhookConfig struct {
+	Enabled bool `json:"enabled"`
+	Port    int  `json:"port"`
+	// The Service in front of the operator pods that the API server calls.
+	ServiceName      string `json:"serviceName"`
+	ServiceNamespace string `json:"serviceNamespace"`
+	// The Secret the self-managed serving certificate is kept in.
+	CertSecretName string `json:"certSecretName"`
+	// Patterns, in the syntax of path.Match, that the image of a MySql has to
+	// match. Empty allows any image.
+	AllowedImages []string `json:"allowedImages"`
+}
+
+func (c webhookConfig) validate() []error {
+	var errs []error
+	if !c.Enabled {
+		return nil
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("webhook.port: must be between 1 and 65535, got %d", c.Port))
+	}
+	if c.ServiceName == "" {
+		errs = append(errs, fmt.Errorf("webhook.serviceName: must not be empty"))
+	}
+	if c.CertSecretName == "" {
+		errs = append(errs, fmt.Errorf("webhook.certSecretName: must not be empty"))
+	}
+	for _, pattern := range c.AllowedImages {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("webhook.allowedImages: %q is not a valid pattern: %v", pattern, err))
+		}
+	}
+	return errs
+}
+
+// The names the webhook Service is reached by inside the cluster.
+func (c webhookConfig) dnsNames() []string {
+	return []string{
+		fmt.Sprintf("%s.%s.svc", c.ServiceName, c.ServiceNamespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", c.ServiceName, c.ServiceNamespace),
+	}
+}
+
+// webhookServer answers admission reviews for MySql objects and the claims
+// backing them.
+type webhookServer struct {
+	config         webhookConfig
+	defaults       instanceDefaults
+	clientset      kubernetes.Interface
+	mySqlClientset mysqlclient.MyprojectV1beta1Interface
+	recorder       record.EventRecorder
+
+	lock sync.RWMutex
+	// The serving certificate and the CA it is signed by, set by loadCert.
+	cert     *tls.Certificate
+	caBundle []byte
+}
+
+// How often the serving certificate is checked. ensureCertSecret replaces it
+// a while before it expires, which has to be well within that time.
+const certCheckInterval = 12 * time.Hour
+
+func newWebhookServer(config webhookConfig, defaults instanceDefaults, clientset kubernetes.Interface, mySqlClientset mysqlclient.MyprojectV1beta1Interface) *webhookServer {
+	return &webhookServer{
+		config:         config,
+		defaults:       defaults,
+		clientset:      clientset,
+		mySqlClientset: mySqlClientset,
+		recorder:       newEventRecorder(clientset),
+	}
+}
+
+// start makes sure a serving certificate exists, registers the webhook
+// configurations with the API server and starts serving. Every replica serves
+// webhooks, whether or not it is the leader.
+func (w *webhookServer) start() error {
+	if _, err := w.loadCert(); err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(validateDeletePath, admissionHandler(w.validateDelete))
+	mux.Handle(validateMySqlPath, admissionHandler(w.validateMySql))
+	mux.Handle(mutateMySqlPath, admissionHandler(w.defaultMySql))
+	mux.HandleFunc(convertPath, conversionHandler)
+	server := &http.Server{
+		Addr:      fmt.Sprintf(":%d", w.config.Port),
+		Handler:   mux,
+		TLSConfig: &tls.Config{GetCertificate: w.getCertificate},
+	}
+	go func() {
+		log.Info("serving admission webhooks", zap.Int("port", w.config.Port))
+		if err := server.ListenAndServeTLS("", ""); err != nil {
+			log.Fatal("failed to serve admission webhooks", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+func (w *webhookServer) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.cert, nil
+}
+
+// loadCert renews the serving certificate when it is close to expiry, serves
+// whatever the Secret holds from then on, and points the webhook
+// configurations at its CA when that changed. Reports whether it did.
+func (w *webhookServer) loadCert() (bool, error) {
+	bundle, err := ensureCertSecret(w.clientset, w.config.ServiceNamespace, w.config.CertSecretName, w.config.dnsNames(), nil)
+	if err != nil {
+		return false, err
+	}
+	cert, err := tls.X509KeyPair(bundle.cert, bundle.key)
+	if err != nil {
+		return false, fmt.Errorf("failed to load serving certificate. %+v", err)
+	}
+	w.lock.Lock()
+	w.cert = &cert
+	caChanged := !bytes.Equal(w.caBundle, bundle.caCert)
+	w.lock.Unlock()
+	if !caChanged {
+		return false, nil
+	}
+
+	if err := w.registerValidatingWebhooks(bundle.caCert); err != nil {
+		return false, err
+	}
+	if err := w.registerMutatingWebhook(bundle.caCert); err != nil {
+		return false, err
+	}
+	// Only remembered once registered, so a failure is retried next time.
+	w.lock.Lock()
+	w.caBundle = bundle.caCert
+	w.lock.Unlock()
+	return true, nil
+}
+
+// rotateCerts checks the serving certificate every certCheckInterval until
+// stop is closed, and calls caChanged when its CA was replaced so whatever
+// else trusts the CA can be updated.
+func (w *webhookServer) rotateCerts(stop <-chan struct{}, caChanged func() error) {
+	ticker := time.NewTicker(certCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		changed, err := w.loadCert()
+		if err != nil {
+			log.Error("failed to renew the webhook serving certificate", zap.Error(err))
+			continue
+		}
+		if !changed {
+			continue
+		}
+		log.Info("webhook certificate authority replaced")
+		if err := caChanged(); err != nil {
+			log.Error("failed to update the webhook certificate authority", zap.Error(err))
+		}
+	}
+}
+
+// How the API server reaches the webhook served on path.
+func (w *webhookServer) clientConfig(webhookPath string, caBundle []byte) admissionregistration.WebhookClientConfig {
+	return admissionregistration.WebhookClientConfig{
+		Service: &admissionregistration.ServiceReference{
+			Namespace: w.config.ServiceNamespace,
+			Name:      w.config.ServiceName,
+			Path:      &webhookPath,
+		},
+		CABundle: caBundle,
+	}
+}
+
+// A rule matching operations on MySql objects. Webhooks using it match
+// equivalently, so requests made through any version are sent to them as
+// the storage version.
+func mySqlRule(operations ...admissionregistration.OperationType) admissionregistration.RuleWithOperations {
+	return admissionregistration.RuleWithOperations{
+		Operations: operations,
+		Rule: admissionregistration.Rule{
+			APIGroups:   []string{mysql.SchemeGroupVersion.Group},
+			APIVersions: []string{mysql.SchemeGroupVersion.Version},
+			Resources:   []string{mysql.MySqlPlural},
+		},
+	}
+}
+
+// Create or update the ValidatingWebhookConfiguration pointing at this
+// operator.
+func (w *webhookServer) registerValidatingWebhooks(caBundle []byte) error {
+	ignore := admissionregistration.Ignore
+	fail := admissionregistration.Fail
+	none := admissionregistration.SideEffectClassNone
+	equivalent := admissionregistration.Equivalent
+	desired := &admissionregistration.ValidatingWebhookConfiguration{
+		ObjectMeta: meta_v1.ObjectMeta{Name: webhookConfigName},
+		Webhooks: []admissionregistration.ValidatingWebhook{
+			{
+				Name:         "deletion-protection.myproject.io",
+				ClientConfig: w.clientConfig(validateDeletePath, caBundle),
+				Rules:        []admissionregistration.RuleWithOperations{mySqlRule(admissionregistration.Delete)},
+				// The finalizers still protect the objects while the operator
+				// is unavailable, so there is no need to block every delete in
+				// the cluster on it.
+				FailurePolicy:           &ignore,
+				MatchPolicy:             &equivalent,
+				SideEffects:             &none,
+				AdmissionReviewVersions: reviewVersions,
+			},
+			{
+				// Only the claims the operator created are sent, not every
+				// claim deleted in the cluster.
+				Name:         "volume-deletion-protection.myproject.io",
+				ClientConfig: w.clientConfig(validateDeletePath, caBundle),
+				Rules: []admissionregistration.RuleWithOperations{{
+					Operations: []admissionregistration.OperationType{admissionregistration.Delete},
+					Rule: admissionregistration.Rule{
+						APIGroups:   []string{""},
+						APIVersions: []string{"v1"},
+						Resources:   []string{"persistentvolumeclaims"},
+					},
+				}},
+				ObjectSelector: &meta_v1.LabelSelector{
+					MatchLabels: map[string]string{myproject.ManagedByLabel: myproject.ManagedByValue},
+				},
+				FailurePolicy:           &ignore,
+				MatchPolicy:             &equivalent,
+				SideEffects:             &none,
+				AdmissionReviewVersions: reviewVersions,
+			},
+			{
+				Name:                    "validation.myproject.io",
+				ClientConfig:            w.clientConfig(validateMySqlPath, caBundle),
+				Rules:                   []admissionregistration.RuleWithOperations{mySqlRule(admissionregistration.Create, admissionregistration.Update)},
+				FailurePolicy:           &fail,
+				MatchPolicy:             &equivalent,
+				SideEffects:             &none,
+				AdmissionReviewVersions: reviewVersions,
+			},
+		},
+	}
+
+	configs := w.clientset.AdmissionregistrationV1().ValidatingWebhookConfigurations()
+	existing, err := configs.Get(context.TODO(), webhookConfigName, meta_v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = configs.Create(context.TODO(), desired, meta_v1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	desired.ResourceVersion = existing.ResourceVersion
+	_, err = configs.Update(context.TODO(), desired, meta_v1.UpdateOptions{})
+	return err
+}
+
+// Create or update the MutatingWebhookConfiguration pointing at this operator.
+func (w *webhookServer) registerMutatingWebhook(caBundle []byte) error {
+	// The controller falls back to the same defaults, so MySql objects can
+	// still be created while the operator is unavailable.
+	ignore := admissionregistration.Ignore
+	none := admissionregistration.SideEffectClassNone
+	equivalent := admissionregistration.Equivalent
+	desired := &admissionregistration.MutatingWebhookConfiguration{
+		ObjectMeta: meta_v1.ObjectMeta{Name: webhookConfigName},
+		Webhooks: []admissionregistration.MutatingWebhook{
+			{
+				Name:                    "defaults.myproject.io",
+				ClientConfig:            w.clientConfig(mutateMySqlPath, caBundle),
+				Rules:                   []admissionregistration.RuleWithOperations{mySqlRule(admissionregistration.Create)},
+				FailurePolicy:           &ignore,
+				MatchPolicy:             &equivalent,
+				SideEffects:             &none,
+				AdmissionReviewVersions: reviewVersions,
+			},
+		},
+	}
+
+	configs := w.clientset.AdmissionregistrationV1().MutatingWebhookConfigurations()
+	existing, err := configs.Get(context.TODO(), webhookConfigName, meta_v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = configs.Create(context.TODO(), desired, meta_v1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	desired.ResourceVersion = existing.ResourceVersion
+	_, err = configs.Update(context.TODO(), desired, meta_v1.UpdateOptions{})
+	return err
+}
+
+// admissionHandler decodes an AdmissionReview, passes the request to review
+// and writes back its response.
+func admissionHandler(review func(*admission.AdmissionRequest) *admission.AdmissionResponse) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var ar admission.AdmissionReview
+		if err := json.Unmarshal(body, &ar); err != nil || ar.Request == nil {
+			http.Error(rw, fmt.Sprintf("failed to decode admission review: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		response := review(ar.Request)
+		response.UID = ar.Request.UID
+		ar.Response = response
+		ar.Request = nil
+
+		out, err := json.Marshal(ar)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write(out)
+	}
+}
+
+func allow() *admission.AdmissionResponse {
+	return &admission.AdmissionResponse{Allowed: true}
+}
+
+func deny(code int32, format string, args ...interface{}) *admission.AdmissionResponse {
+	reason := meta_v1.StatusReasonForbidden
+	switch code {
+	case http.StatusBadRequest:
+		reason = meta_v1.StatusReasonBadRequest
+	case http.StatusUnprocessableEntity:
+		reason = meta_v1.StatusReasonInvalid
+	case http.StatusInternalServerError:
+		reason = meta_v1.StatusReasonInternalError
+	}
+	return &admission.AdmissionResponse{
+		Allowed: false,
+		Result: &meta_v1.Status{
+			Status:  meta_v1.StatusFailure,
+			Code:    code,
+			Reason:  reason,
+			Message: fmt.Sprintf(format, args...),
+		},
+	}
+}
+
+// validateDelete refuses to delete a MySql, or the claim backing one, while
+// the MySql has deletion protection enabled.
+func (w *webhookServer) validateDelete(req *admission.AdmissionRequest) *admission.AdmissionResponse {
+	if req.Operation != admission.Delete {
+		return allow()
+	}
+
+	var name string
+	switch req.Resource.Resource {
+	case mysql.MySqlPlural:
+		name = req.Name
+	case "persistentvolumeclaims":
+		if !strings.HasSuffix(req.Name, myproject.PVCNameSuffix) {
+			return allow()
+		}
+		name = strings.TrimSuffix(req.Name, myproject.PVCNameSuffix)
+	default:
+		return allow()
+	}
+
+	s, err := w.mySqlClientset.MySqls(req.Namespace).Get(context.TODO(), name, meta_v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return allow()
+	}
+	if err != nil {
+		return deny(http.StatusInternalServerError, "failed to look up MySql %s/%s: %v", req.Namespace, name, err)
+	}
+	if !deletionProtected(s) {
+		return allow()
+	}
+
+	log.Info("refused delete of protected instance",
+		zap.String("namespace", req.Namespace),
+		zap.String("name", name),
+		zap.String("resource", req.Resource.Resource),
+		zap.String("user", req.UserInfo.Username))
+	w.recorder.Eventf(s, v1.EventTypeWarning, reasonDeleteBlocked,
+		"Refused to delete %s %s requested by %s: deletion protection is enabled", req.Resource.Resource, req.Name, req.UserInfo.Username)
+	return deny(http.StatusForbidden,
+		"MySql %s/%s has deletion protection enabled; set spec.deletionProtection to false and remove the %s annotation before deleting it or its volume",
+		req.Namespace, name, deletionProtectionAnnotation)
+}
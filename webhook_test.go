@@ -0,0 +1,151 @@
+/*
+Copyright 2018 Tony Allen. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/tonya11en/mysql-operator/pkg/apis/myproject"
+	"k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLoadCert(t *testing.T) {
+	kube := kubefake.NewSimpleClientset()
+	w := newWebhookServer(webhookConfig{
+		ServiceName:      "mysql-operator-webhook",
+		ServiceNamespace: "ops",
+		CertSecretName:   "webhook-cert",
+	}, instanceDefaults{}, kube, nil)
+	secrets := kube.CoreV1().Secrets("ops")
+
+	// The CA the webhook configurations trust, failing the test unless both
+	// trust the same one.
+	registeredCA := func() []byte {
+		t.Helper()
+		validating, err := kube.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(context.TODO(), webhookConfigName, meta_v1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mutating, err := kube.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(context.TODO(), webhookConfigName, meta_v1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ca := mutating.Webhooks[0].ClientConfig.CABundle
+		for _, webhook := range validating.Webhooks {
+			if !bytes.Equal(webhook.ClientConfig.CABundle, ca) {
+				t.Fatalf("webhook %s trusts a different CA", webhook.Name)
+			}
+		}
+		return ca
+	}
+	servedCert := func() []byte {
+		t.Helper()
+		cert, err := w.getCertificate(nil)
+		if err != nil || cert == nil {
+			t.Fatalf("got certificate %v and error %v, want a certificate", cert, err)
+		}
+		return cert.Certificate[0]
+	}
+	load := func(wantChanged bool) {
+		t.Helper()
+		changed, err := w.loadCert()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed != wantChanged {
+			t.Errorf("got CA changed %v, want %v", changed, wantChanged)
+		}
+	}
+
+	load(true)
+	secret, err := secrets.Get(context.TODO(), "webhook-cert", meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstCA := secret.Data[myproject.CACertKey]
+	if !bytes.Equal(registeredCA(), firstCA) {
+		t.Errorf("webhooks do not trust the CA in the secret")
+	}
+	first := servedCert()
+
+	// Nothing to renew.
+	load(false)
+	if !bytes.Equal(servedCert(), first) {
+		t.Errorf("got a new serving certificate, want the same")
+	}
+
+	// A serving certificate that has to be replaced is, signed by the same CA.
+	secret.Data[v1.TLSCertKey] = []byte("expired")
+	if _, err := secrets.Update(context.TODO(), secret, meta_v1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	load(false)
+	if bytes.Equal(servedCert(), first) {
+		t.Errorf("got the old serving certificate, want a new one")
+	}
+	if !bytes.Equal(registeredCA(), firstCA) {
+		t.Errorf("webhooks no longer trust the first CA")
+	}
+
+	// A new CA is registered with the webhooks.
+	if err := secrets.Delete(context.TODO(), "webhook-cert", meta_v1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	load(true)
+	if bytes.Equal(registeredCA(), firstCA) {
+		t.Errorf("webhooks still trust the first CA")
+	}
+	if conversion := w.conversion(); !bytes.Equal(conversion.Webhook.ClientConfig.CABundle, registeredCA()) {
+		t.Errorf("conversion webhook trusts a different CA")
+	}
+}
+
+// Deletes of claims only reach the webhook when the operator created the
+// claim, while every MySql delete does.
+func TestRegisterValidatingWebhooks(t *testing.T) {
+	kube := kubefake.NewSimpleClientset()
+	w := newWebhookServer(webhookConfig{ServiceName: "mysql-operator-webhook", ServiceNamespace: "ops"}, instanceDefaults{}, kube, nil)
+	if err := w.registerValidatingWebhooks(nil); err != nil {
+		t.Fatal(err)
+	}
+	validating, err := kube.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(context.TODO(), webhookConfigName, meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, webhook := range validating.Webhooks {
+		for _, rule := range webhook.Rules {
+			for _, resource := range rule.Resources {
+				var want map[string]string
+				if resource == "persistentvolumeclaims" {
+					want = map[string]string{myproject.ManagedByLabel: myproject.ManagedByValue}
+				}
+				var got map[string]string
+				if webhook.ObjectSelector != nil {
+					got = webhook.ObjectSelector.MatchLabels
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("got object selector %v for %s in webhook %s, want %v", got, resource, webhook.Name, want)
+				}
+			}
+		}
+	}
+}